@@ -0,0 +1,410 @@
+// Copyright 2021 The go-jenkins AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package agent runs Jenkins inbound agents.
+//
+// An Agent creates its node if it is missing, downloads the agent.jar of the Jenkins controller
+// and keeps the agent process running until its context is done:
+//
+//	a, err := agent.New(client, "agent-1", agent.WithCacheDir("/var/cache/jenkins"))
+//	if err != nil {
+//		return err
+//	}
+//	return a.Run(ctx)
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yarlson/go-jenkins/jenkins"
+)
+
+const (
+	// agentJarName is the file name of the cached agent.jar.
+	agentJarName = "agent.jar"
+	// checksumSuffix is appended to the name of the cached agent.jar to get the name of its checksum file.
+	checksumSuffix = ".sha256"
+	// lastModifiedSuffix is appended to the name of the cached agent.jar to get the name of the file
+	// storing the Last-Modified header it was downloaded with.
+	lastModifiedSuffix = ".last-modified"
+	// secretSuffix is appended to the name of the agent to get the name of the file its secret is passed in.
+	secretSuffix = ".secret"
+
+	defaultRemoteFS   = "/home/jenkins/agent"
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = time.Minute
+)
+
+// Agent runs the inbound agent of a Jenkins node.
+type Agent struct {
+	client *jenkins.Client
+	name   string
+
+	node     *jenkins.Node
+	cacheDir string
+	checksum string
+	java     string
+	javaArgs []string
+	stdout   io.Writer
+	stderr   io.Writer
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	onExit     func(err error)
+}
+
+// Option configures an Agent.
+type Option func(*Agent) error
+
+// WithNode sets the node created if the node of the agent is missing.
+// Its name is replaced by the name of the agent and a JNLP launcher is used if none is set.
+func WithNode(node *jenkins.Node) Option {
+	return func(a *Agent) error {
+		a.node = node
+		return nil
+	}
+}
+
+// WithCacheDir sets the directory agent.jar is cached in and the agent secret is written to,
+// the default is the working directory.
+func WithCacheDir(dir string) Option {
+	return func(a *Agent) error {
+		a.cacheDir = dir
+		return nil
+	}
+}
+
+// WithChecksum pins the hex encoded SHA-256 checksum of agent.jar.
+// A downloaded agent.jar with a different checksum is rejected.
+func WithChecksum(checksum string) Option {
+	return func(a *Agent) error {
+		if _, err := hex.DecodeString(checksum); err != nil || len(checksum) != 2*sha256.Size {
+			return fmt.Errorf("invalid SHA-256 checksum %q", checksum)
+		}
+		a.checksum = strings.ToLower(checksum)
+		return nil
+	}
+}
+
+// WithJava sets the java executable and the JVM options passed before "-jar", the default is "java".
+func WithJava(java string, args ...string) Option {
+	return func(a *Agent) error {
+		a.java = java
+		a.javaArgs = args
+		return nil
+	}
+}
+
+// WithOutput sets where the output of the agent process is written to, it is discarded by default.
+func WithOutput(stdout, stderr io.Writer) Option {
+	return func(a *Agent) error {
+		a.stdout = stdout
+		a.stderr = stderr
+		return nil
+	}
+}
+
+// WithBackoff sets the delay before the agent process is restarted. The delay starts at minDelay
+// and doubles with every restart up to maxDelay. It is reset once the process has run for maxDelay.
+func WithBackoff(minDelay, maxDelay time.Duration) Option {
+	return func(a *Agent) error {
+		if minDelay <= 0 || maxDelay < minDelay {
+			return fmt.Errorf("invalid backoff %s to %s", minDelay, maxDelay)
+		}
+		a.minBackoff = minDelay
+		a.maxBackoff = maxDelay
+		return nil
+	}
+}
+
+// WithExitHandler sets a function called whenever the agent process exits or fails to start,
+// with a nil error if it exited successfully.
+func WithExitHandler(onExit func(err error)) Option {
+	return func(a *Agent) error {
+		a.onExit = onExit
+		return nil
+	}
+}
+
+// New returns an agent for the named node.
+func New(client *jenkins.Client, name string, opts ...Option) (*Agent, error) {
+	a := &Agent{
+		client:     client,
+		name:       name,
+		java:       "java",
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// EnsureNode creates the node of the agent unless it exists.
+func (a *Agent) EnsureNode(ctx context.Context) error {
+	_, resp, err := a.client.Nodes.Get(ctx, a.name)
+	if err == nil {
+		return nil
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return err
+	}
+
+	node := jenkins.Node{RemoteFS: defaultRemoteFS}
+	if a.node != nil {
+		node = *a.node
+	}
+	node.Name = a.name
+	if node.Launcher == nil {
+		node.Launcher = jenkins.DefaultJNLPLauncher()
+	}
+
+	_, _, err = a.client.Nodes.Create(ctx, &node)
+
+	return err
+}
+
+// Download returns the path of agent.jar in the cache directory, downloading it unless it is cached.
+// A cached agent.jar is used if it matches the checksum stored next to it and the pinned checksum, if any,
+// and the controller reports it unmodified since it was downloaded, so it is replaced after a Jenkins upgrade.
+// It is downloaded every time if the controller does not send a Last-Modified header.
+func (a *Agent) Download(ctx context.Context) (string, error) {
+	jar := filepath.Join(a.cacheDir, agentJarName)
+
+	var since time.Time
+	if a.cached(jar) {
+		since = lastModified(jar)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(jar), 0o750); err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(jar), agentJarName+".*")
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	hash := sha256.New()
+	written, resp, err := a.client.Nodes.DownloadAgentJarIfModified(ctx, since, io.MultiWriter(tmp, hash))
+	if err != nil {
+		return "", err
+	}
+
+	if !written {
+		return jar, nil
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if a.checksum != "" && checksum != a.checksum {
+		return "", fmt.Errorf("agent.jar checksum %s does not match %s", checksum, a.checksum)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmp.Name(), jar); err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(jar+checksumSuffix, []byte(checksum+"\n"), 0o644); err != nil {
+		return "", err
+	}
+
+	if header := resp.Header.Get("Last-Modified"); header != "" {
+		return jar, os.WriteFile(jar+lastModifiedSuffix, []byte(header+"\n"), 0o644)
+	}
+
+	if err := os.Remove(jar + lastModifiedSuffix); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	return jar, nil
+}
+
+// cached reports whether the agent.jar at path is intact and matches the pinned checksum.
+func (a *Agent) cached(path string) bool {
+	want, err := os.ReadFile(path + checksumSuffix)
+	if err != nil {
+		return false
+	}
+
+	checksum, err := fileChecksum(path)
+	if err != nil {
+		return false
+	}
+
+	if a.checksum != "" && checksum != a.checksum {
+		return false
+	}
+
+	return checksum == strings.TrimSpace(string(want))
+}
+
+// lastModified returns the Last-Modified header the agent.jar at path was downloaded with,
+// or the zero time if it is unknown.
+func lastModified(path string) time.Time {
+	header, err := os.ReadFile(path + lastModifiedSuffix)
+	if err != nil {
+		return time.Time{}
+	}
+
+	t, err := http.ParseTime(strings.TrimSpace(string(header)))
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Command returns the command running the agent with the agent.jar at the given path.
+// The secret is fetched from Jenkins, so the command is only valid as long as the node exists.
+// It is written to a file only readable by the owner in the cache directory and passed as
+// "-secret @<file>", so it does not show in the process list.
+func (a *Agent) Command(ctx context.Context, jar string) (*exec.Cmd, error) {
+	conn, _, err := a.client.Nodes.GetJNLPConnection(ctx, a.name)
+	if err != nil {
+		return nil, err
+	}
+
+	secretFile, err := a.writeSecret(conn.Secret)
+	if err != nil {
+		return nil, err
+	}
+	conn.Secret = "@" + secretFile
+
+	// Replaces the "java" of the command with the java executable and its options.
+	args := append(append([]string{}, a.javaArgs...), conn.Command(jar)[1:]...)
+
+	cmd := exec.CommandContext(ctx, a.java, args...)
+	cmd.Stdout = a.stdout
+	cmd.Stderr = a.stderr
+
+	return cmd, nil
+}
+
+// writeSecret writes the agent secret to a file only readable by the owner and returns its path.
+func (a *Agent) writeSecret(secret string) (string, error) {
+	if a.cacheDir != "" {
+		if err := os.MkdirAll(a.cacheDir, 0o750); err != nil {
+			return "", err
+		}
+	}
+
+	path := filepath.Join(a.cacheDir, a.name+secretSuffix)
+
+	// The file is created with the permissions or tightened if it existed before.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", err
+	}
+
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	if err := f.Chmod(0o600); err != nil {
+		return "", err
+	}
+
+	if _, err := f.WriteString(secret); err != nil {
+		return "", err
+	}
+
+	return path, f.Close()
+}
+
+// Run creates the node if it is missing, downloads agent.jar and runs the agent until ctx is done.
+// The agent process is restarted with a backoff whenever it exits. Run returns the context error
+// once ctx is done, or an error if the node cannot be created or agent.jar cannot be downloaded.
+func (a *Agent) Run(ctx context.Context) error {
+	if err := a.EnsureNode(ctx); err != nil {
+		return err
+	}
+
+	jar, err := a.Download(ctx)
+	if err != nil {
+		return err
+	}
+
+	backoff := a.minBackoff
+	for {
+		started := time.Now()
+		err := a.run(ctx, jar)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if a.onExit != nil {
+			a.onExit(err)
+		}
+
+		// A process that ran for a while is restarted quickly again.
+		if time.Since(started) >= a.maxBackoff {
+			backoff = a.minBackoff
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > a.maxBackoff {
+			backoff = a.maxBackoff
+		}
+	}
+}
+
+func (a *Agent) run(ctx context.Context, jar string) error {
+	cmd, err := a.Command(ctx, jar)
+	if err != nil {
+		return err
+	}
+
+	return cmd.Run()
+}
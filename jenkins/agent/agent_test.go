@@ -0,0 +1,275 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/yarlson/go-jenkins/jenkins"
+)
+
+// helperEnv makes the test binary act as the agent process, see TestHelperProcess.
+const helperEnv = "GO_JENKINS_AGENT_HELPER"
+
+const agentJNLP = `<jnlp codebase="http://jenkins/computer/agent-1/" spec="1.0+">
+  <application-desc main-class="hudson.remoting.jnlp.Main">
+    <argument>secret</argument>
+    <argument>agent-1</argument>
+    <argument>-workDir</argument>
+    <argument>/home/jenkins/agent</argument>
+    <argument>-url</argument>
+    <argument>http://jenkins/</argument>
+  </application-desc>
+</jnlp>`
+
+type Suite struct {
+	mux    *http.ServeMux
+	server *httptest.Server
+	client *jenkins.Client
+
+	jar       []byte
+	modified  time.Time
+	downloads int32
+	created   int32
+
+	suite.Suite
+}
+
+func TestSuite(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+// TestHelperProcess is run as the agent process. It prints its arguments and fails.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv(helperEnv) != "1" {
+		return
+	}
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	fmt.Println(strings.Join(args[1:], " "))
+	os.Exit(1)
+}
+
+func (s *Suite) SetupTest() {
+	s.jar = []byte("PK agent.jar")
+	s.modified = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	s.downloads = 0
+	s.created = 0
+
+	s.mux = http.NewServeMux()
+	s.server = httptest.NewServer(s.mux)
+
+	client, err := jenkins.NewClient(jenkins.WithBaseURL(s.server.URL), jenkins.WithUserPassword("admin", "admin"))
+	s.Require().NoError(err)
+	s.client = client
+
+	s.mux.HandleFunc("/crumbIssuer/api/json", func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"crumbRequestField":"crumb", "crumb":"crumb"}`))
+		s.NoError(err)
+	})
+	s.mux.HandleFunc(jenkins.NodesAgentJarURL, func(w http.ResponseWriter, r *http.Request) {
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !s.modified.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		atomic.AddInt32(&s.downloads, 1)
+		w.Header().Set("Last-Modified", s.modified.Format(http.TimeFormat))
+		_, err := w.Write(s.jar)
+		s.NoError(err)
+	})
+	s.mux.HandleFunc(jenkins.NodesCreateURL, func(w http.ResponseWriter, r *http.Request) {
+		s.Equal("POST", r.Method)
+		s.Equal("agent-1", r.FormValue("name"))
+		s.Contains(r.FormValue("json"), `"stapler-class":"hudson.slaves.JNLPLauncher"`)
+		atomic.AddInt32(&s.created, 1)
+	})
+	s.mux.HandleFunc(fmt.Sprintf(jenkins.NodesJNLPURL, "agent-1"), func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(agentJNLP))
+		s.NoError(err)
+	})
+}
+
+func (s *Suite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *Suite) checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Suite) TestNewOptionErrors() {
+	_, err := New(s.client, "agent-1", WithChecksum("abc"))
+	s.Error(err)
+
+	_, err = New(s.client, "agent-1", WithBackoff(time.Second, time.Millisecond))
+	s.Error(err)
+
+	_, err = New(s.client, "agent-1", WithBackoff(0, time.Second))
+	s.Error(err)
+}
+
+func (s *Suite) TestEnsureNode() {
+	a, err := New(s.client, "agent-1", WithNode(&jenkins.Node{Name: "ignored", RemoteFS: "/srv/agent"}))
+	s.NoError(err)
+
+	s.NoError(a.EnsureNode(context.Background()))
+	s.Equal(int32(1), s.created)
+
+	s.mux.HandleFunc(fmt.Sprintf(jenkins.NodesGetURL, "agent-1"), func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`<slave><name>agent-1</name></slave>`))
+		s.NoError(err)
+	})
+
+	s.NoError(a.EnsureNode(context.Background()))
+	s.Equal(int32(1), s.created)
+}
+
+func (s *Suite) TestEnsureNodeError() {
+	s.mux.HandleFunc(fmt.Sprintf(jenkins.NodesGetURL, "agent-1"), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	a, err := New(s.client, "agent-1")
+	s.NoError(err)
+
+	s.Error(a.EnsureNode(context.Background()))
+	s.Equal(int32(0), s.created)
+}
+
+func (s *Suite) TestDownload() {
+	dir := s.T().TempDir()
+	a, err := New(s.client, "agent-1", WithCacheDir(filepath.Join(dir, "cache")))
+	s.NoError(err)
+
+	jar, err := a.Download(context.Background())
+	s.NoError(err)
+	s.Equal(filepath.Join(dir, "cache", "agent.jar"), jar)
+
+	data, err := os.ReadFile(jar)
+	s.NoError(err)
+	s.Equal(s.jar, data)
+
+	sum, err := os.ReadFile(jar + ".sha256")
+	s.NoError(err)
+	s.Equal(s.checksum(s.jar)+"\n", string(sum))
+
+	modified, err := os.ReadFile(jar + ".last-modified")
+	s.NoError(err)
+	s.Equal(s.modified.Format(http.TimeFormat)+"\n", string(modified))
+
+	// The cached agent.jar is used as long as it is intact and not modified on the controller.
+	_, err = a.Download(context.Background())
+	s.NoError(err)
+	s.Equal(int32(1), s.downloads)
+
+	s.NoError(os.WriteFile(jar, []byte("corrupted"), 0o644))
+	_, err = a.Download(context.Background())
+	s.NoError(err)
+	s.Equal(int32(2), s.downloads)
+
+	data, err = os.ReadFile(jar)
+	s.NoError(err)
+	s.Equal(s.jar, data)
+
+	// A Jenkins upgrade replaces the cached agent.jar.
+	s.jar = []byte("PK upgraded agent.jar")
+	s.modified = s.modified.Add(24 * time.Hour)
+	_, err = a.Download(context.Background())
+	s.NoError(err)
+	s.Equal(int32(3), s.downloads)
+
+	data, err = os.ReadFile(jar)
+	s.NoError(err)
+	s.Equal(s.jar, data)
+
+	sum, err = os.ReadFile(jar + ".sha256")
+	s.NoError(err)
+	s.Equal(s.checksum(s.jar)+"\n", string(sum))
+}
+
+func (s *Suite) TestDownloadChecksum() {
+	dir := s.T().TempDir()
+
+	a, err := New(s.client, "agent-1", WithCacheDir(dir), WithChecksum(strings.ToUpper(s.checksum(s.jar))))
+	s.NoError(err)
+
+	_, err = a.Download(context.Background())
+	s.NoError(err)
+
+	// A cached agent.jar not matching the pinned checksum is downloaded again and rejected.
+	a, err = New(s.client, "agent-1", WithCacheDir(dir), WithChecksum(s.checksum([]byte("other"))))
+	s.NoError(err)
+
+	_, err = a.Download(context.Background())
+	s.Error(err)
+	s.Equal(int32(2), s.downloads)
+}
+
+func (s *Suite) TestRun() {
+	s.T().Setenv(helperEnv, "1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var stdout bytes.Buffer
+	var exits []error
+	dir := s.T().TempDir()
+	a, err := New(s.client, "agent-1",
+		WithCacheDir(dir),
+		WithJava(os.Args[0], "-test.run=^TestHelperProcess$", "--"),
+		WithOutput(&stdout, nil),
+		WithBackoff(time.Millisecond, 4*time.Millisecond),
+		WithExitHandler(func(err error) {
+			exits = append(exits, err)
+			if len(exits) == 3 {
+				cancel()
+			}
+		}),
+	)
+	s.NoError(err)
+
+	err = a.Run(ctx)
+	s.ErrorIs(err, context.Canceled)
+	s.Len(exits, 3)
+	s.Error(exits[0])
+	s.Equal(int32(1), s.created)
+	s.Equal(int32(1), s.downloads)
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	s.Len(lines, 3)
+	secretFile := filepath.Join(dir, "agent-1.secret")
+	s.Equal("-jar "+filepath.Join(dir, "agent.jar")+" -url http://jenkins/ -secret @"+secretFile+" -name agent-1 -workDir /home/jenkins/agent", lines[0])
+
+	// The secret is passed in a file only readable by the owner, not on the command line.
+	secret, err := os.ReadFile(secretFile)
+	s.NoError(err)
+	s.Equal("secret", string(secret))
+
+	info, err := os.Stat(secretFile)
+	s.NoError(err)
+	s.Equal(os.FileMode(0o600), info.Mode().Perm())
+}
+
+func (s *Suite) TestRunDownloadError() {
+	a, err := New(s.client, "agent-1", WithCacheDir(s.T().TempDir()), WithChecksum(s.checksum([]byte("other"))))
+	s.NoError(err)
+
+	s.Error(a.Run(context.Background()))
+}
@@ -0,0 +1,174 @@
+// Copyright 2021 The go-jenkins AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jenkins
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// The artifact URLs below are formatted with JobPath.URLPath and the build number.
+const (
+	// ArtifactsListURL is the URL to list the artifacts of a build
+	ArtifactsListURL = "%s/%d/api/json?tree=artifacts[displayPath,fileName,relativePath]"
+	// ArtifactsDownloadURL is the URL to download a single artifact of a build
+	ArtifactsDownloadURL = "%s/%d/artifact/%s"
+	// ArtifactsArchiveURL is the URL to download all artifacts of a build as a zip archive
+	ArtifactsArchiveURL = "%s/%d/artifact/*zip*/archive.zip"
+
+	// archiveRoot is the directory Jenkins puts all entries of the artifacts archive in.
+	archiveRoot = "archive/"
+)
+
+// ListArtifacts returns the artifacts archived by a build.
+func (s *BuildsService) ListArtifacts(ctx context.Context, job string, number int) ([]Artifact, *http.Response, error) {
+	var build Build
+	resp, err := s.client.getJSON(ctx, fmt.Sprintf(ArtifactsListURL, JobPath(job).URLPath(), number), &build)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return build.Artifacts, resp, nil
+}
+
+// DownloadArtifact writes the artifact with the given relative path, see Artifact.RelativePath, to w.
+func (s *BuildsService) DownloadArtifact(ctx context.Context, job string, number int, relativePath string, w io.Writer) (*http.Response, error) {
+	segments := strings.Split(relativePath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return s.client.download(ctx, fmt.Sprintf(ArtifactsDownloadURL, JobPath(job).URLPath(), number, strings.Join(segments, "/")), w)
+}
+
+// DownloadArchive writes a zip archive of all artifacts of a build to w.
+// Jenkins puts all files of the archive into an "archive" directory.
+func (s *BuildsService) DownloadArchive(ctx context.Context, job string, number int, w io.Writer) (*http.Response, error) {
+	return s.client.download(ctx, fmt.Sprintf(ArtifactsArchiveURL, JobPath(job).URLPath(), number), w)
+}
+
+// ExtractArchive downloads all artifacts of a build and unpacks them into dir, keeping their relative paths.
+// Entries escaping dir, e.g. through "..", and symbolic links are rejected.
+func (s *BuildsService) ExtractArchive(ctx context.Context, job string, number int, dir string) (*http.Response, error) {
+	tmp, err := os.CreateTemp("", "jenkins-archive-*.zip")
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	resp, err := s.DownloadArchive(ctx, job, number, tmp)
+	if err != nil {
+		return resp, err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return resp, err
+	}
+
+	archive, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, extractZip(archive, dir)
+}
+
+// extractZip unpacks a Jenkins artifacts archive into dir.
+func extractZip(archive *zip.Reader, dir string) error {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range archive.File {
+		name := strings.TrimPrefix(f.Name, archiveRoot)
+		if name == "" {
+			continue
+		}
+
+		target, err := archiveTarget(root, name)
+		if err != nil {
+			return err
+		}
+
+		if f.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("archive entry %q is a symbolic link", f.Name)
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0o750); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := extractZipFile(f, target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// archiveTarget returns the path an archive entry is unpacked to.
+// It fails if the entry would end up outside of root.
+func archiveTarget(root, name string) (string, error) {
+	if path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("archive entry %q has an absolute path", name)
+	}
+
+	target := filepath.Join(root, filepath.FromSlash(path.Clean(name)))
+	if !strings.HasPrefix(target, root+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry %q is outside of the target directory", name)
+	}
+
+	return target, nil
+}
+
+func extractZipFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+		return err
+	}
+
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+
+	defer func(src io.ReadCloser) {
+		_ = src.Close()
+	}(src)
+
+	mode := f.Mode().Perm()
+	if mode == 0 {
+		mode = 0o644
+	}
+
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+
+	return dst.Close()
+}
@@ -0,0 +1,158 @@
+package jenkins
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+func (s *Suite) newArchive(files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		s.Require().NoError(err)
+		_, err = f.Write([]byte(content))
+		s.Require().NoError(err)
+	}
+	s.Require().NoError(w.Close())
+
+	return buf.Bytes()
+}
+
+func (s *Suite) TestBuildsServiceListArtifacts() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc("/job/deploy/3/api/json", func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		s.Equal("artifacts[displayPath,fileName,relativePath]", r.URL.Query().Get("tree"))
+		_, err := w.Write([]byte(`{"artifacts":[{"displayPath":"app","fileName":"app","relativePath":"bin/app"}]}`))
+		s.NoError(err)
+	})
+
+	artifacts, _, err := client.Builds.ListArtifacts(context.Background(), "deploy", 3)
+	s.NoError(err)
+	s.Equal([]Artifact{{DisplayPath: "app", FileName: "app", RelativePath: "bin/app"}}, artifacts)
+
+	_, _, err = client.Builds.ListArtifacts(context.Background(), "deploy", 4)
+	s.Error(err)
+}
+
+func (s *Suite) TestBuildsServiceDownloadArtifact() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc("/job/deploy/3/artifact/bin/my app", func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		s.Equal("/job/deploy/3/artifact/bin/my%20app", r.URL.EscapedPath())
+		_, err := w.Write([]byte{0x7f, 'E', 'L', 'F'})
+		s.NoError(err)
+	})
+
+	buf := &bytes.Buffer{}
+	_, err = client.Builds.DownloadArtifact(context.Background(), "deploy", 3, "bin/my app", buf)
+	s.NoError(err)
+	s.Equal([]byte{0x7f, 'E', 'L', 'F'}, buf.Bytes())
+
+	_, err = client.Builds.DownloadArtifact(context.Background(), "deploy", 3, "missing", buf)
+	s.Error(err)
+}
+
+func (s *Suite) TestBuildsServiceDownloadArchive() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	archive := s.newArchive(map[string]string{"archive/bin/app": "app"})
+	s.mux.HandleFunc(fmt.Sprintf(ArtifactsArchiveURL, "/job/deploy", 3), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write(archive)
+		s.NoError(err)
+	})
+
+	buf := &bytes.Buffer{}
+	_, err = client.Builds.DownloadArchive(context.Background(), "deploy", 3, buf)
+	s.NoError(err)
+	s.Equal(archive, buf.Bytes())
+}
+
+func (s *Suite) TestBuildsServiceExtractArchive() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	archive := s.newArchive(map[string]string{
+		"archive/bin/app":      "app",
+		"archive/docs/":        "",
+		"archive/docs/README":  "readme",
+		"archive/bin/../a.txt": "a",
+	})
+	s.mux.HandleFunc(fmt.Sprintf(ArtifactsArchiveURL, "/job/deploy", 3), func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write(archive)
+		s.NoError(err)
+	})
+
+	dir := s.T().TempDir()
+	_, err = client.Builds.ExtractArchive(context.Background(), "deploy", 3, dir)
+	s.NoError(err)
+
+	for name, content := range map[string]string{"bin/app": "app", "docs/README": "readme", "a.txt": "a"} {
+		b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		s.NoError(err)
+		s.Equal(content, string(b))
+	}
+}
+
+func (s *Suite) TestBuildsServiceExtractArchiveError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(ArtifactsArchiveURL, "/job/deploy", 3), func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte("not a zip"))
+		s.NoError(err)
+	})
+
+	_, err = client.Builds.ExtractArchive(context.Background(), "deploy", 3, s.T().TempDir())
+	s.Error(err)
+
+	_, err = client.Builds.ExtractArchive(context.Background(), "missing", 3, s.T().TempDir())
+	s.Error(err)
+}
+
+func (s *Suite) TestExtractZipPathTraversal() {
+	for _, name := range []string{"../evil", "archive/../../evil", "/etc/evil", "archive/a/../../../evil"} {
+		archive := s.newArchive(map[string]string{name: "evil"})
+		r, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		s.NoError(err)
+
+		dir := s.T().TempDir()
+		s.Error(extractZip(r, filepath.Join(dir, "target")), name)
+
+		_, err = os.Stat(filepath.Join(dir, "evil"))
+		s.True(os.IsNotExist(err), name)
+	}
+}
+
+func (s *Suite) TestExtractZipSymlink() {
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	header := &zip.FileHeader{Name: "archive/link"}
+	header.SetMode(os.ModeSymlink | 0o777)
+	f, err := w.CreateHeader(header)
+	s.NoError(err)
+	_, err = f.Write([]byte("/etc/passwd"))
+	s.NoError(err)
+	s.NoError(w.Close())
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	s.NoError(err)
+	s.Error(extractZip(r, s.T().TempDir()))
+}
@@ -0,0 +1,578 @@
+// Copyright 2021 The go-jenkins AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jenkins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// The build URLs below are formatted with JobPath.URLPath and the build number.
+const (
+	// BuildsTriggerURL is the URL to trigger a build without parameters
+	BuildsTriggerURL = "%s/build"
+	// BuildsTriggerWithParametersURL is the URL to trigger a parameterized build
+	BuildsTriggerWithParametersURL = "%s/buildWithParameters"
+	// BuildsGetURL is the URL to get a build
+	BuildsGetURL = "%s/%d/api/json"
+	// BuildsGetPermalinkURL is the URL to get a build by permalink
+	BuildsGetPermalinkURL = "%s/%s/api/json"
+	// BuildsProgressiveLogURL is the URL to read the console output of a build in chunks
+	BuildsProgressiveLogURL = "%s/%d/logText/progressiveText"
+	// BuildsStopURL is the URL to abort a running build
+	BuildsStopURL = "%s/%d/stop"
+	// BuildsTermURL is the URL to forcibly terminate a running Pipeline build
+	BuildsTermURL = "%s/%d/term"
+	// BuildsKillURL is the URL to hard kill a running Pipeline build
+	BuildsKillURL = "%s/%d/kill"
+	// BuildsDeleteURL is the URL to delete a build
+	BuildsDeleteURL = "%s/%d/doDelete"
+	// BuildsToggleKeepURL is the URL to toggle whether a build is kept forever
+	BuildsToggleKeepURL = "%s/%d/toggleLogKeep"
+	// BuildsDescriptionURL is the URL to set the description of a build
+	BuildsDescriptionURL = "%s/%d/submitDescription"
+	// BuildsConfigURL is the URL to set the display name and description of a build
+	BuildsConfigURL = "%s/%d/configSubmit"
+)
+
+// queueItemLocation matches the queue item URL returned in the Location header of a triggered build.
+var queueItemLocation = regexp.MustCompile(`/queue/item/(\d+)/?$`)
+
+// BuildResult represents the result of a finished Jenkins build.
+type BuildResult string
+
+const (
+	// BuildResultSuccess is the result of a successful build
+	BuildResultSuccess BuildResult = "SUCCESS"
+	// BuildResultUnstable is the result of a build with test failures
+	BuildResultUnstable BuildResult = "UNSTABLE"
+	// BuildResultFailure is the result of a failed build
+	BuildResultFailure BuildResult = "FAILURE"
+	// BuildResultNotBuilt is the result of a build that was not built, e.g. a skipped stage
+	BuildResultNotBuilt BuildResult = "NOT_BUILT"
+	// BuildResultAborted is the result of an aborted build
+	BuildResultAborted BuildResult = "ABORTED"
+)
+
+// Permalink represents a Jenkins build permalink such as "lastBuild".
+type Permalink string
+
+const (
+	// PermalinkLastBuild refers to the last build
+	PermalinkLastBuild Permalink = "lastBuild"
+	// PermalinkLastCompletedBuild refers to the last completed build
+	PermalinkLastCompletedBuild Permalink = "lastCompletedBuild"
+	// PermalinkLastSuccessfulBuild refers to the last successful build
+	PermalinkLastSuccessfulBuild Permalink = "lastSuccessfulBuild"
+	// PermalinkLastStableBuild refers to the last stable build
+	PermalinkLastStableBuild Permalink = "lastStableBuild"
+	// PermalinkLastUnstableBuild refers to the last unstable build
+	PermalinkLastUnstableBuild Permalink = "lastUnstableBuild"
+	// PermalinkLastFailedBuild refers to the last failed build
+	PermalinkLastFailedBuild Permalink = "lastFailedBuild"
+	// PermalinkLastUnsuccessfulBuild refers to the last build that was not successful
+	PermalinkLastUnsuccessfulBuild Permalink = "lastUnsuccessfulBuild"
+)
+
+const (
+	// CauseUserID is the class of a cause of a build started by a user
+	CauseUserID = "hudson.model.Cause$UserIdCause"
+	// CauseUpstream is the class of a cause of a build started by an upstream build
+	CauseUpstream = "hudson.model.Cause$UpstreamCause"
+	// CauseSCMTrigger is the class of a cause of a build started by SCM polling
+	CauseSCMTrigger = "hudson.triggers.SCMTrigger$SCMTriggerCause"
+	// CauseTimerTrigger is the class of a cause of a build started by a timer
+	CauseTimerTrigger = "hudson.triggers.TimerTrigger$TimerTriggerCause"
+)
+
+// Build represents a Jenkins build.
+type Build struct {
+	Class             string        `json:"_class"`
+	ID                string        `json:"id"`
+	Number            int           `json:"number"`
+	URL               string        `json:"url"`
+	DisplayName       string        `json:"displayName"`
+	FullDisplayName   string        `json:"fullDisplayName"`
+	Description       string        `json:"description"`
+	Building          bool          `json:"building"`
+	Result            BuildResult   `json:"result"`
+	Timestamp         int64         `json:"timestamp"`
+	Duration          int64         `json:"duration"`
+	EstimatedDuration int64         `json:"estimatedDuration"`
+	QueueID           int64         `json:"queueId"`
+	KeepLog           bool          `json:"keepLog"`
+	BuiltOn           string        `json:"builtOn"`
+	Actions           []BuildAction `json:"actions"`
+	Artifacts         []Artifact    `json:"artifacts"`
+	ChangeSet         *ChangeSet    `json:"changeSet"`
+	ChangeSets        []ChangeSet   `json:"changeSets"`
+}
+
+// StartTime returns the time the build was started.
+func (b *Build) StartTime() time.Time {
+	return time.UnixMilli(b.Timestamp)
+}
+
+// Causes returns the causes the build was started by.
+func (b *Build) Causes() []Cause {
+	var causes []Cause
+	for _, action := range b.Actions {
+		causes = append(causes, action.Causes...)
+	}
+
+	return causes
+}
+
+// Parameters returns the parameter values the build was started with.
+func (b *Build) Parameters() []ParameterValue {
+	var params []ParameterValue
+	for _, action := range b.Actions {
+		params = append(params, action.Parameters...)
+	}
+
+	return params
+}
+
+// AllChangeSets returns the change sets of the build.
+// Freestyle builds report a single change set, Pipeline builds one per checkout.
+func (b *Build) AllChangeSets() []ChangeSet {
+	if b.ChangeSet != nil {
+		return append([]ChangeSet{*b.ChangeSet}, b.ChangeSets...)
+	}
+
+	return b.ChangeSets
+}
+
+// BuildAction represents an action attached to a build.
+// Only the causes and parameters actions are decoded.
+type BuildAction struct {
+	Class      string           `json:"_class"`
+	Causes     []Cause          `json:"causes"`
+	Parameters []ParameterValue `json:"parameters"`
+}
+
+// Cause represents the cause of a build, e.g. CauseUserID or CauseUpstream.
+type Cause struct {
+	Class            string `json:"_class"`
+	ShortDescription string `json:"shortDescription"`
+	UserID           string `json:"userId"`
+	UserName         string `json:"userName"`
+	UpstreamProject  string `json:"upstreamProject"`
+	UpstreamBuild    int    `json:"upstreamBuild"`
+	UpstreamURL      string `json:"upstreamUrl"`
+}
+
+// ParameterValue represents the value of a build parameter.
+type ParameterValue struct {
+	Class string      `json:"_class"`
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+// Artifact represents a file archived by a build.
+type Artifact struct {
+	DisplayPath  string `json:"displayPath"`
+	FileName     string `json:"fileName"`
+	RelativePath string `json:"relativePath"`
+}
+
+// ChangeSet represents the SCM changes of a build.
+type ChangeSet struct {
+	Class string          `json:"_class"`
+	Kind  string          `json:"kind"`
+	Items []ChangeSetItem `json:"items"`
+}
+
+// ChangeSetItem represents a single commit of a change set.
+type ChangeSetItem struct {
+	Class         string          `json:"_class"`
+	CommitID      string          `json:"commitId"`
+	Msg           string          `json:"msg"`
+	Comment       string          `json:"comment"`
+	Timestamp     int64           `json:"timestamp"`
+	Author        ChangeSetAuthor `json:"author"`
+	AuthorEmail   string          `json:"authorEmail"`
+	AffectedPaths []string        `json:"affectedPaths"`
+}
+
+// ChangeSetAuthor represents the author of a commit.
+type ChangeSetAuthor struct {
+	AbsoluteURL string `json:"absoluteUrl"`
+	FullName    string `json:"fullName"`
+}
+
+// BuildParameter is a parameter value of a parameterized build.
+// Supported parameters are StringParameter, BooleanParameter, ChoiceParameter and FileParameter,
+// passed as values or pointers.
+type BuildParameter interface {
+	parameterName() string
+}
+
+// dereferenceParameter returns the value of a parameter passed as a pointer.
+func dereferenceParameter(param BuildParameter) BuildParameter {
+	switch p := param.(type) {
+	case *StringParameter:
+		if p != nil {
+			return *p
+		}
+	case *BooleanParameter:
+		if p != nil {
+			return *p
+		}
+	case *ChoiceParameter:
+		if p != nil {
+			return *p
+		}
+	case *FileParameter:
+		if p != nil {
+			return *p
+		}
+	}
+
+	return param
+}
+
+// StringParameter represents a string parameter value.
+type StringParameter struct {
+	Name  string
+	Value string
+}
+
+func (p StringParameter) parameterName() string {
+	return p.Name
+}
+
+// BooleanParameter represents a boolean parameter value.
+type BooleanParameter struct {
+	Name  string
+	Value bool
+}
+
+func (p BooleanParameter) parameterName() string {
+	return p.Name
+}
+
+// ChoiceParameter represents a choice parameter value.
+type ChoiceParameter struct {
+	Name  string
+	Value string
+}
+
+func (p ChoiceParameter) parameterName() string {
+	return p.Name
+}
+
+// FileParameter represents a file parameter value. The content is uploaded as FileName.
+type FileParameter struct {
+	Name     string
+	FileName string
+	Content  io.Reader
+}
+
+func (p FileParameter) parameterName() string {
+	return p.Name
+}
+
+// BuildsService handles communication with the build related methods of the Jenkins API.
+// Job names are full names as described by JobPath.
+type BuildsService service
+
+// Trigger schedules a build of a job and returns the ID of the created queue item.
+// Parameterized jobs are triggered with buildWithParameters, file parameters are sent as a multipart form.
+func (s *BuildsService) Trigger(ctx context.Context, job string, params ...BuildParameter) (int64, *http.Response, error) {
+	resp, err := s.trigger(ctx, JobPath(job).URLPath(), params)
+	if err != nil {
+		return 0, resp, err
+	}
+
+	location := resp.Header.Get("Location")
+	match := queueItemLocation.FindStringSubmatch(location)
+	if match == nil {
+		return 0, resp, fmt.Errorf("unexpected queue item location: %q", location)
+	}
+
+	id, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, resp, err
+	}
+
+	return id, resp, nil
+}
+
+func (s *BuildsService) trigger(ctx context.Context, jobURL string, params []BuildParameter) (*http.Response, error) {
+	if len(params) == 0 {
+		return s.client.post(ctx, fmt.Sprintf(BuildsTriggerURL, jobURL), nil)
+	}
+
+	path := fmt.Sprintf(BuildsTriggerWithParametersURL, jobURL)
+	values := make(url.Values)
+	var files []FileParameter
+
+	for _, param := range params {
+		switch p := dereferenceParameter(param).(type) {
+		case StringParameter:
+			values.Add(p.Name, p.Value)
+		case BooleanParameter:
+			values.Add(p.Name, strconv.FormatBool(p.Value))
+		case ChoiceParameter:
+			values.Add(p.Name, p.Value)
+		case FileParameter:
+			files = append(files, p)
+		default:
+			return nil, fmt.Errorf("unsupported parameter type %T", p)
+		}
+	}
+
+	if len(files) == 0 {
+		return s.client.postForm(ctx, path, values)
+	}
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for name, vs := range values {
+		for _, v := range vs {
+			if err := writer.WriteField(name, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, file := range files {
+		part, err := writer.CreateFormFile(file.Name, file.FileName)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(part, file.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return s.client.postBody(ctx, path, writer.FormDataContentType(), body)
+}
+
+// WaitForBuild polls a queue item until it has been turned into a build and returns the build.
+// It fails if the queue item is cancelled.
+func (s *BuildsService) WaitForBuild(ctx context.Context, queueID int64) (*Build, *http.Response, error) {
+	var item *QueueItem
+	var resp *http.Response
+
+	err := s.client.poll(ctx, func() (bool, error) {
+		var err error
+		item, resp, err = s.client.Queue.Get(ctx, queueID)
+		if err != nil {
+			return false, err
+		}
+
+		if item.Cancelled {
+			return false, fmt.Errorf("queue item %d was cancelled", queueID)
+		}
+
+		return item.Executable != nil, nil
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	job, err := ParseJobURL(item.Task.URL)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return s.Get(ctx, string(job), item.Executable.Number)
+}
+
+// Get returns a build of a job.
+func (s *BuildsService) Get(ctx context.Context, job string, number int) (*Build, *http.Response, error) {
+	var build Build
+	resp, err := s.client.getJSON(ctx, fmt.Sprintf(BuildsGetURL, JobPath(job).URLPath(), number), &build)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &build, resp, nil
+}
+
+// GetPermalink returns the build of a job a permalink refers to, e.g. PermalinkLastSuccessfulBuild.
+// Jenkins responds with 404 Not Found if there is no such build.
+func (s *BuildsService) GetPermalink(ctx context.Context, job string, permalink Permalink) (*Build, *http.Response, error) {
+	var build Build
+	resp, err := s.client.getJSON(ctx, fmt.Sprintf(BuildsGetPermalinkURL, JobPath(job).URLPath(), permalink), &build)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &build, resp, nil
+}
+
+// Stop aborts a running build, like the stop button in the UI.
+func (s *BuildsService) Stop(ctx context.Context, job string, number int) (*http.Response, error) {
+	return s.client.post(ctx, fmt.Sprintf(BuildsStopURL, JobPath(job).URLPath(), number), nil)
+}
+
+// Term forcibly terminates a running Pipeline build that did not react to Stop.
+func (s *BuildsService) Term(ctx context.Context, job string, number int) (*http.Response, error) {
+	return s.client.post(ctx, fmt.Sprintf(BuildsTermURL, JobPath(job).URLPath(), number), nil)
+}
+
+// Kill hard kills a running Pipeline build that did not react to Term.
+// The build is not given any chance to clean up.
+func (s *BuildsService) Kill(ctx context.Context, job string, number int) (*http.Response, error) {
+	return s.client.post(ctx, fmt.Sprintf(BuildsKillURL, JobPath(job).URLPath(), number), nil)
+}
+
+// Delete deletes a build.
+func (s *BuildsService) Delete(ctx context.Context, job string, number int) (*http.Response, error) {
+	return s.client.post(ctx, fmt.Sprintf(BuildsDeleteURL, JobPath(job).URLPath(), number), nil)
+}
+
+// ToggleKeepForever toggles whether a build is kept forever, i.e. excluded from log rotation.
+func (s *BuildsService) ToggleKeepForever(ctx context.Context, job string, number int) (*http.Response, error) {
+	return s.client.post(ctx, fmt.Sprintf(BuildsToggleKeepURL, JobPath(job).URLPath(), number), nil)
+}
+
+// SetKeepForever sets whether a build is kept forever. The build is left untouched if it already is in that state.
+func (s *BuildsService) SetKeepForever(ctx context.Context, job string, number int, keep bool) (*http.Response, error) {
+	build, resp, err := s.Get(ctx, job, number)
+	if err != nil || build.KeepLog == keep {
+		return resp, err
+	}
+
+	return s.ToggleKeepForever(ctx, job, number)
+}
+
+// SetDescription sets the description of a build.
+func (s *BuildsService) SetDescription(ctx context.Context, job string, number int, description string) (*http.Response, error) {
+	return s.client.postForm(ctx, fmt.Sprintf(BuildsDescriptionURL, JobPath(job).URLPath(), number), url.Values{
+		"description": {description},
+	})
+}
+
+// SetDisplayName sets the display name of a build, an empty name restores the default "#<number>".
+// Jenkins expects the description to be submitted along, so the current one is fetched first.
+func (s *BuildsService) SetDisplayName(ctx context.Context, job string, number int, displayName string) (*http.Response, error) {
+	build, resp, err := s.Get(ctx, job, number)
+	if err != nil {
+		return resp, err
+	}
+
+	config, err := json.Marshal(map[string]string{
+		"displayName": displayName,
+		"description": build.Description,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.client.postForm(ctx, fmt.Sprintf(BuildsConfigURL, JobPath(job).URLPath(), number), url.Values{
+		"displayName": {displayName},
+		"description": {build.Description},
+		"json":        {string(config)},
+	})
+}
+
+// StreamLog returns a reader of the console output of a build.
+// The reader follows the output while the build is running and returns io.EOF once the build has finished
+// and the whole output has been read. Reads fail with the context error when ctx is done.
+func (s *BuildsService) StreamLog(ctx context.Context, job string, number int) *LogStream {
+	return &LogStream{
+		ctx:    ctx,
+		client: s.client,
+		path:   fmt.Sprintf(BuildsProgressiveLogURL, JobPath(job).URLPath(), number),
+		more:   true,
+	}
+}
+
+// LogStream reads the console output of a build using the progressiveText endpoint.
+// Jenkins reports the offset of the next chunk in the X-Text-Size header
+// and whether more output is expected in the X-More-Data header.
+type LogStream struct {
+	ctx    context.Context
+	client *Client
+	path   string
+	offset int64
+	buf    []byte
+	more   bool
+	idle   bool
+}
+
+// Read implements the io.Reader interface.
+func (l *LogStream) Read(p []byte) (int, error) {
+	for len(l.buf) == 0 {
+		if !l.more {
+			return 0, io.EOF
+		}
+
+		// Waits only after a chunk without new output.
+		if l.idle {
+			if err := l.wait(); err != nil {
+				return 0, err
+			}
+		}
+
+		if err := l.fetch(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, l.buf)
+	l.buf = l.buf[n:]
+
+	return n, nil
+}
+
+func (l *LogStream) wait() error {
+	timer := time.NewTimer(l.client.pollInterval)
+	defer timer.Stop()
+
+	select {
+	case <-l.ctx.Done():
+		return l.ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (l *LogStream) fetch() error {
+	resp, err := l.client.get(l.ctx, l.path+"?"+url.Values{"start": {strconv.FormatInt(l.offset, 10)}}.Encode())
+	if err != nil {
+		return err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	l.idle = len(body) == 0
+	l.buf = body
+	l.more = resp.Header.Get("X-More-Data") == "true"
+
+	if size, err := strconv.ParseInt(resp.Header.Get("X-Text-Size"), 10, 64); err == nil {
+		l.offset = size
+	} else {
+		l.offset += int64(len(body))
+	}
+
+	return nil
+}
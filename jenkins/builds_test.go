@@ -0,0 +1,480 @@
+package jenkins
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (s *Suite) TestBuildsServiceTrigger() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(BuildsTriggerURL, "/job/team/job/deploy"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		w.Header().Set("Location", s.server.URL+"/queue/item/42/")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	id, _, err := client.Builds.Trigger(context.Background(), "team/deploy")
+	s.NoError(err)
+	s.Equal(int64(42), id)
+}
+
+func (s *Suite) TestBuildsServiceTriggerWithParameters() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(BuildsTriggerWithParametersURL, "/job/deploy"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+		s.Equal("v1", r.FormValue("VERSION"))
+		s.Equal("true", r.FormValue("DRY_RUN"))
+		s.Equal("prod", r.FormValue("ENV"))
+		w.Header().Set("Location", "/queue/item/7/")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	id, _, err := client.Builds.Trigger(context.Background(), "deploy",
+		StringParameter{Name: "VERSION", Value: "v1"},
+		BooleanParameter{Name: "DRY_RUN", Value: true},
+		ChoiceParameter{Name: "ENV", Value: "prod"},
+	)
+	s.NoError(err)
+	s.Equal(int64(7), id)
+}
+
+// passwordParameter is a parameter type Trigger does not support.
+type passwordParameter struct {
+	Name string
+}
+
+func (p passwordParameter) parameterName() string {
+	return p.Name
+}
+
+func (s *Suite) TestBuildsServiceTriggerWithParameterPointers() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	triggered := 0
+	s.mux.HandleFunc(fmt.Sprintf(BuildsTriggerWithParametersURL, "/job/deploy"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("v1", r.FormValue("VERSION"))
+		s.Equal("true", r.FormValue("DRY_RUN"))
+		s.Equal("prod", r.FormValue("ENV"))
+		triggered++
+		w.Header().Set("Location", "/queue/item/7/")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	id, _, err := client.Builds.Trigger(context.Background(), "deploy",
+		&StringParameter{Name: "VERSION", Value: "v1"},
+		&BooleanParameter{Name: "DRY_RUN", Value: true},
+		&ChoiceParameter{Name: "ENV", Value: "prod"},
+	)
+	s.NoError(err)
+	s.Equal(int64(7), id)
+
+	// Unsupported parameters fail instead of being dropped.
+	_, _, err = client.Builds.Trigger(context.Background(), "deploy", passwordParameter{Name: "TOKEN"})
+	s.EqualError(err, "unsupported parameter type jenkins.passwordParameter")
+
+	_, _, err = client.Builds.Trigger(context.Background(), "deploy", (*StringParameter)(nil))
+	s.EqualError(err, "unsupported parameter type *jenkins.StringParameter")
+	s.Equal(1, triggered)
+}
+
+func (s *Suite) TestBuildsServiceTriggerWithFileParameter() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(BuildsTriggerWithParametersURL, "/job/deploy"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("crumb", r.Header.Get("crumb"))
+		s.Equal("v1", r.FormValue("VERSION"))
+
+		file, header, err := r.FormFile("MANIFEST")
+		s.NoError(err)
+		s.Equal("manifest.yaml", header.Filename)
+		content, err := io.ReadAll(file)
+		s.NoError(err)
+		s.Equal("kind: Deployment", string(content))
+
+		w.Header().Set("Location", "/queue/item/8/")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	id, _, err := client.Builds.Trigger(context.Background(), "deploy",
+		StringParameter{Name: "VERSION", Value: "v1"},
+		FileParameter{Name: "MANIFEST", FileName: "manifest.yaml", Content: strings.NewReader("kind: Deployment")},
+	)
+	s.NoError(err)
+	s.Equal(int64(8), id)
+}
+
+func (s *Suite) TestBuildsServiceTriggerNoLocation() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(BuildsTriggerURL, "/job/deploy"), func(w http.ResponseWriter, r *http.Request) {})
+
+	_, _, err = client.Builds.Trigger(context.Background(), "deploy")
+	s.Error(err)
+}
+
+func (s *Suite) TestBuildsServiceTriggerError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	_, _, err = client.Builds.Trigger(context.Background(), "deploy")
+	s.Error(err)
+}
+
+func (s *Suite) TestBuildsServiceWaitForBuild() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"), WithPollInterval(time.Millisecond))
+	s.NoError(err)
+
+	polls := 0
+	s.mux.HandleFunc(fmt.Sprintf(QueueItemURL, 42), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		polls++
+		if polls < 3 {
+			_, err := w.Write([]byte(`{"id":42,"why":"Waiting for next available executor","task":{"url":"` + s.server.URL + `/job/team/job/deploy/"}}`))
+			s.NoError(err)
+			return
+		}
+		_, err := w.Write([]byte(`{"id":42,"task":{"url":"` + s.server.URL + `/job/team/job/deploy/"},"executable":{"number":5,"url":"` + s.server.URL + `/job/team/job/deploy/5/"}}`))
+		s.NoError(err)
+	})
+	s.mux.HandleFunc(fmt.Sprintf(BuildsGetURL, "/job/team/job/deploy", 5), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(`{"number":5,"building":true,"queueId":42}`))
+		s.NoError(err)
+	})
+
+	build, _, err := client.Builds.WaitForBuild(context.Background(), 42)
+	s.NoError(err)
+	s.Equal(3, polls)
+	s.Equal(5, build.Number)
+	s.True(build.Building)
+}
+
+func (s *Suite) TestBuildsServiceWaitForBuildCancelled() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"), WithPollInterval(time.Millisecond))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(QueueItemURL, 42), func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"id":42,"cancelled":true}`))
+		s.NoError(err)
+	})
+
+	_, _, err = client.Builds.WaitForBuild(context.Background(), 42)
+	s.Error(err)
+}
+
+func (s *Suite) TestBuildsServiceWaitForBuildContext() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"), WithPollInterval(time.Hour))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(QueueItemURL, 42), func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"id":42}`))
+		s.NoError(err)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, _, err = client.Builds.WaitForBuild(ctx, 42)
+	s.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func (s *Suite) TestBuildsServiceGet() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(BuildsGetURL, "/job/deploy", 3), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(`{"number":3,"result":"SUCCESS","duration":1200}`))
+		s.NoError(err)
+	})
+
+	build, _, err := client.Builds.Get(context.Background(), "deploy", 3)
+	s.NoError(err)
+	s.Equal(BuildResultSuccess, build.Result)
+	s.Equal(int64(1200), build.Duration)
+
+	_, _, err = client.Builds.Get(context.Background(), "deploy", 4)
+	s.Error(err)
+}
+
+func (s *Suite) TestBuildsServiceStreamLog() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"), WithPollInterval(time.Millisecond))
+	s.NoError(err)
+
+	chunks := []string{"Started\n", "", "Building\n", "Finished: SUCCESS\n"}
+	offset := 0
+	s.mux.HandleFunc(fmt.Sprintf(BuildsProgressiveLogURL, "/job/team/job/deploy", 5), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		s.Equal(fmt.Sprint(offset), r.URL.Query().Get("start"))
+
+		chunk := chunks[0]
+		chunks = chunks[1:]
+		offset += len(chunk)
+
+		w.Header().Set("X-Text-Size", fmt.Sprint(offset))
+		if len(chunks) > 0 {
+			w.Header().Set("X-More-Data", "true")
+		}
+		_, err := w.Write([]byte(chunk))
+		s.NoError(err)
+	})
+
+	log, err := io.ReadAll(client.Builds.StreamLog(context.Background(), "team/deploy", 5))
+	s.NoError(err)
+	s.Equal("Started\nBuilding\nFinished: SUCCESS\n", string(log))
+	s.Empty(chunks)
+}
+
+func (s *Suite) TestBuildsServiceStreamLogContext() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"), WithPollInterval(time.Hour))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(BuildsProgressiveLogURL, "/job/deploy", 5), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-More-Data", "true")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = io.ReadAll(client.Builds.StreamLog(ctx, "deploy", 5))
+	s.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func (s *Suite) TestBuildsServiceStreamLogError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	_, err = io.ReadAll(client.Builds.StreamLog(context.Background(), "deploy", 5))
+	s.Error(err)
+}
+
+func (s *Suite) TestBuildsServiceGetDetails() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(BuildsGetURL, "/job/deploy", 12), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(`{
+  "_class": "org.jenkinsci.plugins.workflow.job.WorkflowRun",
+  "actions": [
+    {"_class": "hudson.model.ParametersAction", "parameters": [{"_class": "hudson.model.StringParameterValue", "name": "VERSION", "value": "v1"}]},
+    {"_class": "hudson.model.CauseAction", "causes": [
+      {"_class": "hudson.model.Cause$UpstreamCause", "shortDescription": "Started by upstream project \"build\" build number 3", "upstreamBuild": 3, "upstreamProject": "build", "upstreamUrl": "job/build/"},
+      {"_class": "hudson.model.Cause$UserIdCause", "shortDescription": "Started by user admin", "userId": "admin", "userName": "admin"}
+    ]},
+    {},
+    {"_class": "hudson.plugins.git.util.BuildData"}
+  ],
+  "artifacts": [{"displayPath": "app.tar.gz", "fileName": "app.tar.gz", "relativePath": "dist/app.tar.gz"}],
+  "building": false,
+  "duration": 61234,
+  "estimatedDuration": 60000,
+  "fullDisplayName": "deploy #12",
+  "id": "12",
+  "keepLog": true,
+  "number": 12,
+  "queueId": 99,
+  "result": "FAILURE",
+  "timestamp": 1700000000000,
+  "changeSets": [{
+    "_class": "hudson.plugins.git.GitChangeSetList",
+    "kind": "git",
+    "items": [{"commitId": "abc123", "msg": "Fix deploy", "timestamp": 1699999999000, "author": {"fullName": "dev"}, "affectedPaths": ["Jenkinsfile"]}]
+  }]
+}`))
+		s.NoError(err)
+	})
+
+	build, _, err := client.Builds.Get(context.Background(), "deploy", 12)
+	s.NoError(err)
+	s.Equal(BuildResultFailure, build.Result)
+	s.Equal(int64(61234), build.Duration)
+	s.True(build.KeepLog)
+	s.Equal(time.UnixMilli(1700000000000), build.StartTime())
+
+	causes := build.Causes()
+	s.Len(causes, 2)
+	s.Equal(CauseUpstream, causes[0].Class)
+	s.Equal("build", causes[0].UpstreamProject)
+	s.Equal(3, causes[0].UpstreamBuild)
+	s.Equal(CauseUserID, causes[1].Class)
+	s.Equal("admin", causes[1].UserID)
+
+	s.Equal([]ParameterValue{{Class: "hudson.model.StringParameterValue", Name: "VERSION", Value: "v1"}}, build.Parameters())
+
+	s.Equal("dist/app.tar.gz", build.Artifacts[0].RelativePath)
+
+	changeSets := build.AllChangeSets()
+	s.Len(changeSets, 1)
+	s.Equal("abc123", changeSets[0].Items[0].CommitID)
+	s.Equal("dev", changeSets[0].Items[0].Author.FullName)
+}
+
+func (s *Suite) TestBuildAllChangeSetsFreestyle() {
+	build := &Build{ChangeSet: &ChangeSet{Kind: "git"}}
+	s.Equal([]ChangeSet{{Kind: "git"}}, build.AllChangeSets())
+}
+
+func (s *Suite) TestBuildsServiceGetPermalink() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(BuildsGetPermalinkURL, "/job/team/job/deploy", PermalinkLastSuccessfulBuild), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(`{"number":11,"result":"SUCCESS"}`))
+		s.NoError(err)
+	})
+
+	build, _, err := client.Builds.GetPermalink(context.Background(), "team/deploy", PermalinkLastSuccessfulBuild)
+	s.NoError(err)
+	s.Equal(11, build.Number)
+
+	_, resp, err := client.Builds.GetPermalink(context.Background(), "team/deploy", PermalinkLastFailedBuild)
+	s.Error(err)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (s *Suite) TestBuildsServiceLifecycle() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	var called []string
+	for _, action := range []string{"stop", "term", "kill", "doDelete", "toggleLogKeep"} {
+		action := action
+		s.mux.HandleFunc("/job/team/job/deploy/5/"+action, func(w http.ResponseWriter, r *http.Request) {
+			s.testMethod(r, "POST")
+			s.Equal("crumb", r.Header.Get("crumb"))
+			called = append(called, action)
+		})
+	}
+
+	ctx := context.Background()
+	_, err = client.Builds.Stop(ctx, "team/deploy", 5)
+	s.NoError(err)
+	_, err = client.Builds.Term(ctx, "team/deploy", 5)
+	s.NoError(err)
+	_, err = client.Builds.Kill(ctx, "team/deploy", 5)
+	s.NoError(err)
+	_, err = client.Builds.Delete(ctx, "team/deploy", 5)
+	s.NoError(err)
+	_, err = client.Builds.ToggleKeepForever(ctx, "team/deploy", 5)
+	s.NoError(err)
+
+	s.Equal([]string{"stop", "term", "kill", "doDelete", "toggleLogKeep"}, called)
+
+	_, err = client.Builds.Stop(ctx, "team/deploy", 6)
+	s.Error(err)
+}
+
+func (s *Suite) TestBuildsServiceSetKeepForever() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(BuildsGetURL, "/job/deploy", 5), func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"number":5,"keepLog":true}`))
+		s.NoError(err)
+	})
+
+	toggled := 0
+	s.mux.HandleFunc(fmt.Sprintf(BuildsToggleKeepURL, "/job/deploy", 5), func(w http.ResponseWriter, r *http.Request) {
+		toggled++
+	})
+
+	_, err = client.Builds.SetKeepForever(context.Background(), "deploy", 5, true)
+	s.NoError(err)
+	s.Equal(0, toggled)
+
+	_, err = client.Builds.SetKeepForever(context.Background(), "deploy", 5, false)
+	s.NoError(err)
+	s.Equal(1, toggled)
+
+	_, err = client.Builds.SetKeepForever(context.Background(), "deploy", 6, false)
+	s.Error(err)
+}
+
+func (s *Suite) TestBuildsServiceSetDescription() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(BuildsDescriptionURL, "/job/deploy", 5), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("Deployed v1", r.FormValue("description"))
+	})
+
+	_, err = client.Builds.SetDescription(context.Background(), "deploy", 5, "Deployed v1")
+	s.NoError(err)
+}
+
+func (s *Suite) TestBuildsServiceSetDisplayName() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(BuildsGetURL, "/job/deploy", 5), func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"number":5,"description":"Deployed v1"}`))
+		s.NoError(err)
+	})
+
+	s.mux.HandleFunc(fmt.Sprintf(BuildsConfigURL, "/job/deploy", 5), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.JSONEq(`{"displayName":"v1","description":"Deployed v1"}`, r.FormValue("json"))
+	})
+
+	_, err = client.Builds.SetDisplayName(context.Background(), "deploy", 5, "v1")
+	s.NoError(err)
+
+	_, err = client.Builds.SetDisplayName(context.Background(), "deploy", 6, "v1")
+	s.Error(err)
+}
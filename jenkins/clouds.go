@@ -0,0 +1,312 @@
+// Copyright 2021 The go-jenkins AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jenkins
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Cloud represents a Jenkins cloud provisioning agents, e.g. a Kubernetes or Docker cloud.
+type Cloud struct {
+	Name  string `json:"name"`
+	Class string `json:"class"`
+
+	// ServerURL and Namespace are set for Kubernetes clouds.
+	ServerURL string `json:"serverUrl,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// PodTemplate represents a pod template of a Kubernetes cloud.
+type PodTemplate struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Label is the space separated list of labels of the agents started from the template.
+	Label       string              `json:"label"`
+	IdleMinutes int                 `json:"idleMinutes"`
+	Containers  []ContainerTemplate `json:"containers"`
+}
+
+// ContainerTemplate represents a container of a Kubernetes pod template.
+type ContainerTemplate struct {
+	Name                  string `json:"name"`
+	Image                 string `json:"image"`
+	Command               string `json:"command"`
+	Args                  string `json:"args"`
+	WorkingDir            string `json:"workingDir"`
+	TTYEnabled            bool   `json:"ttyEnabled"`
+	Privileged            bool   `json:"privileged"`
+	AlwaysPullImage       bool   `json:"alwaysPullImage"`
+	ResourceRequestCPU    string `json:"resourceRequestCpu"`
+	ResourceRequestMemory string `json:"resourceRequestMemory"`
+	ResourceLimitCPU      string `json:"resourceLimitCpu"`
+	ResourceLimitMemory   string `json:"resourceLimitMemory"`
+}
+
+// CloudsService handles communication with the cloud related methods of the Jenkins API.
+// Clouds have no REST API, so they are read and configured with Groovy scripts run in the script console,
+// which requires the Overall/Administer permission.
+type CloudsService service
+
+// cloudsScriptImports starts every cloud script, Groovy allows imports at the start of a script only.
+const cloudsScriptImports = `import groovy.json.JsonOutput
+import groovy.json.JsonSlurper
+import jenkins.model.Jenkins
+`
+
+// cloudsScriptHelpers is appended to every cloud script. It decodes the data passed by runScript
+// and prints the result of the run method, or the message of an IllegalArgumentException it throws, as JSON.
+const cloudsScriptHelpers = `
+def kubernetesCloud(name) {
+    def cloud = Jenkins.get().clouds.getByName(name)
+    if (cloud == null) {
+        throw new IllegalArgumentException("cloud " + name + " not found")
+    }
+    if (cloud.getClass().name != "org.csanchez.jenkins.plugins.kubernetes.KubernetesCloud") {
+        throw new IllegalArgumentException("cloud " + name + " is not a Kubernetes cloud")
+    }
+    return cloud
+}
+
+def podTemplate(cloud, name) {
+    def template = cloud.templates.find { it.name == name }
+    if (template == null) {
+        throw new IllegalArgumentException("pod template " + name + " not found in cloud " + cloud.name)
+    }
+    return template
+}
+
+def podTemplateToMap(t) {
+    return [
+        name: t.name,
+        namespace: t.namespace,
+        label: t.label,
+        idleMinutes: t.idleMinutes,
+        containers: t.containers.collect { c -> [
+            name: c.name,
+            image: c.image,
+            command: c.command,
+            args: c.args,
+            workingDir: c.workingDir,
+            ttyEnabled: c.ttyEnabled,
+            privileged: c.privileged,
+            alwaysPullImage: c.alwaysPullImage,
+            resourceRequestCpu: c.resourceRequestCpu,
+            resourceRequestMemory: c.resourceRequestMemory,
+            resourceLimitCpu: c.resourceLimitCpu,
+            resourceLimitMemory: c.resourceLimitMemory
+        ]}
+    ]
+}
+
+// Containers are updated in place, so settings not set from the map, e.g. environment variables, are kept.
+def applyPodTemplate(t, m) {
+    t.name = m.name
+    t.namespace = m.namespace
+    t.label = m.label
+    t.idleMinutes = m.idleMinutes
+
+    def loader = Jenkins.get().pluginManager.uberClassLoader
+    def containerClass = loader.loadClass("org.csanchez.jenkins.plugins.kubernetes.ContainerTemplate")
+    def existing = t.containers.collectEntries { [(it.name): it] }
+    t.containers = (m.containers ?: []).collect { cm ->
+        def c = existing[cm.name] ?: containerClass.newInstance(cm.name, cm.image)
+        c.image = cm.image
+        c.command = cm.command
+        c.args = cm.args
+        c.workingDir = cm.workingDir
+        c.ttyEnabled = cm.ttyEnabled
+        c.privileged = cm.privileged
+        c.alwaysPullImage = cm.alwaysPullImage
+        c.resourceRequestCpu = cm.resourceRequestCpu
+        c.resourceRequestMemory = cm.resourceRequestMemory
+        c.resourceLimitCpu = cm.resourceLimitCpu
+        c.resourceLimitMemory = cm.resourceLimitMemory
+        return c
+    }
+}
+
+def output = [:]
+try {
+    output.result = run(new JsonSlurper().parseText(new String(data.decodeBase64(), "UTF-8")))
+} catch (IllegalArgumentException e) {
+    output.error = e.message
+}
+println(JsonOutput.toJson(output))
+`
+
+const cloudsListScript = `
+def run(data) {
+    return Jenkins.get().clouds.collect { c -> [
+        name: c.name,
+        "class": c.getClass().name,
+        serverUrl: c.hasProperty("serverUrl") ? c.serverUrl : null,
+        namespace: c.hasProperty("namespace") ? c.namespace : null
+    ]}
+}
+`
+
+const cloudsListPodTemplatesScript = `
+def run(data) {
+    return kubernetesCloud(data.cloud).templates.collect { podTemplateToMap(it) }
+}
+`
+
+const cloudsGetPodTemplateScript = `
+def run(data) {
+    return podTemplateToMap(podTemplate(kubernetesCloud(data.cloud), data.name))
+}
+`
+
+const cloudsCreatePodTemplateScript = `
+def run(data) {
+    def cloud = kubernetesCloud(data.cloud)
+    if (cloud.templates.find { it.name == data.template.name } != null) {
+        throw new IllegalArgumentException("pod template " + data.template.name + " already exists in cloud " + cloud.name)
+    }
+    def loader = Jenkins.get().pluginManager.uberClassLoader
+    def template = loader.loadClass("org.csanchez.jenkins.plugins.kubernetes.PodTemplate").newInstance()
+    applyPodTemplate(template, data.template)
+    cloud.addTemplate(template)
+    Jenkins.get().save()
+    return podTemplateToMap(template)
+}
+`
+
+const cloudsUpdatePodTemplateScript = `
+def run(data) {
+    def template = podTemplate(kubernetesCloud(data.cloud), data.name)
+    applyPodTemplate(template, data.template)
+    Jenkins.get().save()
+    return podTemplateToMap(template)
+}
+`
+
+const cloudsDeletePodTemplateScript = `
+def run(data) {
+    def cloud = kubernetesCloud(data.cloud)
+    cloud.removeTemplate(podTemplate(cloud, data.name))
+    Jenkins.get().save()
+    return null
+}
+`
+
+// podTemplateRequest is the data passed to the pod template scripts.
+type podTemplateRequest struct {
+	Cloud    string       `json:"cloud"`
+	Name     string       `json:"name,omitempty"`
+	Template *PodTemplate `json:"template,omitempty"`
+}
+
+// cloudsScriptOutput is the JSON printed by the cloud scripts.
+type cloudsScriptOutput struct {
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error"`
+}
+
+// runScript runs the cloud script with data passed as base64 encoded JSON, so it needs no escaping,
+// and decodes the result printed by the script into v.
+func (s *CloudsService) runScript(ctx context.Context, script string, data interface{}, v interface{}) (*http.Response, error) {
+	if data == nil {
+		data = struct{}{}
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	script = cloudsScriptImports +
+		fmt.Sprintf("def data = \"%s\"\n", base64.StdEncoding.EncodeToString(b)) +
+		script +
+		cloudsScriptHelpers
+
+	body, resp, err := s.client.Script(ctx, script)
+	if err != nil {
+		return resp, err
+	}
+
+	var output cloudsScriptOutput
+	if err := json.Unmarshal([]byte(strings.TrimSpace(body)), &output); err != nil {
+		return resp, fmt.Errorf("unexpected script output: %s", strings.TrimSpace(body))
+	}
+
+	if output.Error != "" {
+		return resp, fmt.Errorf("%s", output.Error)
+	}
+
+	if v == nil {
+		return resp, nil
+	}
+
+	return resp, json.Unmarshal(output.Result, v)
+}
+
+// List returns the clouds configured in Jenkins.
+func (s *CloudsService) List(ctx context.Context) ([]Cloud, *http.Response, error) {
+	var clouds []Cloud
+	resp, err := s.runScript(ctx, cloudsListScript, nil, &clouds)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return clouds, resp, nil
+}
+
+// ListPodTemplates returns the pod templates of a Kubernetes cloud.
+func (s *CloudsService) ListPodTemplates(ctx context.Context, cloud string) ([]PodTemplate, *http.Response, error) {
+	var templates []PodTemplate
+	resp, err := s.runScript(ctx, cloudsListPodTemplatesScript, &podTemplateRequest{Cloud: cloud}, &templates)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return templates, resp, nil
+}
+
+// GetPodTemplate returns a pod template of a Kubernetes cloud.
+func (s *CloudsService) GetPodTemplate(ctx context.Context, cloud, name string) (*PodTemplate, *http.Response, error) {
+	var template PodTemplate
+	resp, err := s.runScript(ctx, cloudsGetPodTemplateScript, &podTemplateRequest{Cloud: cloud, Name: name}, &template)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &template, resp, nil
+}
+
+// CreatePodTemplate adds a pod template to a Kubernetes cloud.
+func (s *CloudsService) CreatePodTemplate(ctx context.Context, cloud string, template *PodTemplate) (*PodTemplate, *http.Response, error) {
+	var created PodTemplate
+	resp, err := s.runScript(ctx, cloudsCreatePodTemplateScript, &podTemplateRequest{Cloud: cloud, Template: template}, &created)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &created, resp, nil
+}
+
+// UpdatePodTemplate updates the named pod template of a Kubernetes cloud, which is renamed if the name
+// of the template differs. Containers are matched by name, so their settings not modelled by
+// ContainerTemplate, e.g. environment variables and ports, are kept.
+func (s *CloudsService) UpdatePodTemplate(ctx context.Context, cloud, name string, template *PodTemplate) (*PodTemplate, *http.Response, error) {
+	var updated PodTemplate
+	resp, err := s.runScript(ctx, cloudsUpdatePodTemplateScript, &podTemplateRequest{Cloud: cloud, Name: name, Template: template}, &updated)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &updated, resp, nil
+}
+
+// DeletePodTemplate removes a pod template from a Kubernetes cloud.
+func (s *CloudsService) DeletePodTemplate(ctx context.Context, cloud, name string) (*http.Response, error) {
+	return s.runScript(ctx, cloudsDeletePodTemplateScript, &podTemplateRequest{Cloud: cloud, Name: name}, nil)
+}
@@ -0,0 +1,177 @@
+package jenkins
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var scriptDataRegexp = regexp.MustCompile(`def data = "([A-Za-z0-9+/=]*)"`)
+
+// handleCloudsScript serves the script console, passing the data of the posted cloud script to handle
+// and writing what it returns.
+func (s *Suite) handleCloudsScript(handle func(script string, data map[string]interface{}) string) {
+	s.mux.HandleFunc(ScriptURL, func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("crumb", r.Header.Get("crumb"))
+
+		script := r.FormValue("script")
+		s.True(strings.HasPrefix(script, "import groovy.json.JsonOutput\n"))
+
+		m := scriptDataRegexp.FindStringSubmatch(script)
+		s.Len(m, 2)
+
+		b, err := base64.StdEncoding.DecodeString(m[1])
+		s.NoError(err)
+
+		var data map[string]interface{}
+		s.NoError(json.Unmarshal(b, &data))
+
+		_, err = w.Write([]byte(handle(script, data)))
+		s.NoError(err)
+	})
+}
+
+func (s *Suite) TestCloudsServiceList() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+	s.handleCloudsScript(func(script string, data map[string]interface{}) string {
+		s.Contains(script, "Jenkins.get().clouds.collect")
+		s.Empty(data)
+		return `{"result":[{"name":"kubernetes","class":"org.csanchez.jenkins.plugins.kubernetes.KubernetesCloud","serverUrl":"https://k8s","namespace":"jenkins"},{"name":"docker","class":"com.nirima.jenkins.plugins.docker.DockerCloud","serverUrl":null,"namespace":null}]}
+`
+	})
+
+	clouds, _, err := client.Clouds.List(context.Background())
+	s.NoError(err)
+	s.Equal([]Cloud{
+		{
+			Name:      "kubernetes",
+			Class:     "org.csanchez.jenkins.plugins.kubernetes.KubernetesCloud",
+			ServerURL: "https://k8s",
+			Namespace: "jenkins",
+		},
+		{
+			Name:  "docker",
+			Class: "com.nirima.jenkins.plugins.docker.DockerCloud",
+		},
+	}, clouds)
+}
+
+const podTemplateJSON = `{"name":"maven","namespace":"ci","label":"maven java","idleMinutes":10,"containers":[{"name":"maven","image":"maven:3-jdk-11","command":"sleep","args":"infinity","workingDir":"/home/jenkins/agent","ttyEnabled":true,"privileged":false,"alwaysPullImage":false,"resourceRequestCpu":"500m","resourceRequestMemory":"1Gi","resourceLimitCpu":"","resourceLimitMemory":"2Gi"}]}`
+
+var mavenPodTemplate = PodTemplate{
+	Name:        "maven",
+	Namespace:   "ci",
+	Label:       "maven java",
+	IdleMinutes: 10,
+	Containers: []ContainerTemplate{
+		{
+			Name:                  "maven",
+			Image:                 "maven:3-jdk-11",
+			Command:               "sleep",
+			Args:                  "infinity",
+			WorkingDir:            "/home/jenkins/agent",
+			TTYEnabled:            true,
+			ResourceRequestCPU:    "500m",
+			ResourceRequestMemory: "1Gi",
+			ResourceLimitMemory:   "2Gi",
+		},
+	},
+}
+
+func (s *Suite) TestCloudsServicePodTemplates() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+	s.handleCloudsScript(func(script string, data map[string]interface{}) string {
+		s.Equal("kubernetes", data["cloud"])
+
+		switch {
+		case strings.Contains(script, "templates.collect { podTemplateToMap(it) }"):
+			return `{"result":[` + podTemplateJSON + `]}`
+		case strings.Contains(script, "cloud.addTemplate(template)"):
+			s.Equal("maven", data["template"].(map[string]interface{})["name"])
+			return `{"result":` + podTemplateJSON + `}`
+		case strings.Contains(script, "applyPodTemplate(template, data.template)"):
+			s.Equal("old-maven", data["name"])
+			s.Equal("maven", data["template"].(map[string]interface{})["name"])
+			return `{"result":` + podTemplateJSON + `}`
+		case strings.Contains(script, "cloud.removeTemplate"):
+			s.Equal("maven", data["name"])
+			return `{"result":null}`
+		default:
+			s.Equal("maven", data["name"])
+			return `{"result":` + podTemplateJSON + `}`
+		}
+	})
+
+	templates, _, err := client.Clouds.ListPodTemplates(context.Background(), "kubernetes")
+	s.NoError(err)
+	s.Equal([]PodTemplate{mavenPodTemplate}, templates)
+
+	template, _, err := client.Clouds.GetPodTemplate(context.Background(), "kubernetes", "maven")
+	s.NoError(err)
+	s.Equal(&mavenPodTemplate, template)
+
+	created, _, err := client.Clouds.CreatePodTemplate(context.Background(), "kubernetes", &mavenPodTemplate)
+	s.NoError(err)
+	s.Equal(&mavenPodTemplate, created)
+
+	updated, _, err := client.Clouds.UpdatePodTemplate(context.Background(), "kubernetes", "old-maven", &mavenPodTemplate)
+	s.NoError(err)
+	s.Equal(&mavenPodTemplate, updated)
+
+	_, err = client.Clouds.DeletePodTemplate(context.Background(), "kubernetes", "maven")
+	s.NoError(err)
+}
+
+func (s *Suite) TestCloudsServiceScriptErrors() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+	s.handleCloudsScript(func(script string, data map[string]interface{}) string {
+		switch data["name"] {
+		case "missing":
+			return `{"error":"pod template missing not found in cloud kubernetes"}`
+		case "broken":
+			return "java.lang.NoSuchMethodError: setImage\n\tat Script1.applyPodTemplate(Script1.groovy:42)\n"
+		}
+		return "groovy.lang.MissingPropertyException: No such property: templates for class: hudson.slaves.Cloud\n"
+	})
+
+	_, _, err = client.Clouds.GetPodTemplate(context.Background(), "kubernetes", "missing")
+	s.EqualError(err, "pod template missing not found in cloud kubernetes")
+
+	_, _, err = client.Clouds.UpdatePodTemplate(context.Background(), "kubernetes", "broken", &mavenPodTemplate)
+	s.EqualError(err, "script error: java.lang.NoSuchMethodError: setImage")
+	s.IsType(&ScriptError{}, err)
+
+	_, _, err = client.Clouds.ListPodTemplates(context.Background(), "kubernetes")
+	s.EqualError(err, "unexpected script output: groovy.lang.MissingPropertyException: No such property: templates for class: hudson.slaves.Cloud")
+}
+
+func (s *Suite) TestCloudsServiceHTTPError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+	s.mux.HandleFunc(ScriptURL, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	_, resp, err := client.Clouds.List(context.Background())
+	s.Error(err)
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+}
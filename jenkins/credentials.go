@@ -0,0 +1,318 @@
+// Copyright 2021 The go-jenkins AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jenkins
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+// The credentials URLs below are formatted with CredentialsStore.URLPath,
+// e.g. "/credentials/store/system/domain/_".
+const (
+	// CredentialsListURL is the URL to list the credentials of a store domain
+	CredentialsListURL = "%s/api/json"
+	// CredentialsCreateURL is the URL to create a credential in a store domain
+	CredentialsCreateURL = "%s/createCredentials"
+	// CredentialsConfigURL is the URL to get or update a credential
+	CredentialsConfigURL = "%s/credential/%s/config.xml"
+	// CredentialsDeleteURL is the URL to delete a credential
+	CredentialsDeleteURL = "%s/credential/%s/doDelete"
+)
+
+// RedactedSecret is the value of a secret redacted by Jenkins in a credential returned by CredentialsService.Get.
+// Recent versions of the credentials plugin redact all secrets of config.xml.
+const RedactedSecret = "<secret-redacted/>"
+
+// redactedSecretRegexp matches the element Jenkins replaces a secret with, including the surrounding indentation.
+var redactedSecretRegexp = regexp.MustCompile(`\s*<secret-redacted\s*/>\s*`)
+
+// redactedSecretXML is RedactedSecret as marshalled into a text element.
+const redactedSecretXML = "&lt;secret-redacted/&gt;"
+
+// CredentialScope is the scope of a credential.
+type CredentialScope string
+
+const (
+	// CredentialScopeGlobal credentials are available to jobs and the system.
+	CredentialScopeGlobal CredentialScope = "GLOBAL"
+	// CredentialScopeSystem credentials are available to the system only, e.g. to launch agents.
+	CredentialScopeSystem CredentialScope = "SYSTEM"
+)
+
+// CredentialsStore identifies a domain of a credentials store.
+// The zero value is the global domain of the system store.
+type CredentialsStore struct {
+	// Folder is the full name of the folder of a folder store as described by JobPath,
+	// empty for the system store.
+	Folder string
+	// Domain is the name of the credentials domain, empty for the global domain.
+	Domain string
+}
+
+// URLPath returns the escaped Jenkins URL path of the store domain,
+// e.g. "/job/team/credentials/store/folder/domain/_".
+func (s CredentialsStore) URLPath() string {
+	domain := s.Domain
+	if domain == "" {
+		domain = "_"
+	}
+
+	if s.Folder == "" {
+		return "/credentials/store/system/domain/" + url.PathEscape(domain)
+	}
+
+	return JobPath(s.Folder).URLPath() + "/credentials/store/folder/domain/" + url.PathEscape(domain)
+}
+
+// Credential is the interface for all Jenkins credentials, e.g. UsernamePasswordCredentials.
+type Credential interface{}
+
+// UsernamePasswordCredentials represents a username with password credential.
+type UsernamePasswordCredentials struct {
+	XMLName xml.Name `xml:"com.cloudbees.plugins.credentials.impl.UsernamePasswordCredentialsImpl"`
+
+	Scope       CredentialScope `xml:"scope"`
+	ID          string          `xml:"id"`
+	Description string          `xml:"description"`
+	Username    string          `xml:"username"`
+	Password    string          `xml:"password"`
+	// UsernameSecret masks the username in build logs like the password.
+	UsernameSecret bool `xml:"usernameSecret"`
+}
+
+// SSHUserPrivateKeyCredentials represents an SSH username with private key credential.
+type SSHUserPrivateKeyCredentials struct {
+	XMLName xml.Name `xml:"com.cloudbees.jenkins.plugins.sshcredentials.impl.BasicSSHUserPrivateKey"`
+
+	Scope            CredentialScope   `xml:"scope"`
+	ID               string            `xml:"id"`
+	Description      string            `xml:"description"`
+	Username         string            `xml:"username"`
+	UsernameSecret   bool              `xml:"usernameSecret"`
+	Passphrase       string            `xml:"passphrase,omitempty"`
+	PrivateKeySource *PrivateKeySource `xml:"privateKeySource"`
+}
+
+// PrivateKeySource represents the private key of an SSH credential entered directly.
+type PrivateKeySource struct {
+	StaplerClass string `xml:"class,attr"`
+
+	PrivateKey string `xml:"privateKey"`
+}
+
+// NewSSHUserPrivateKeyCredentials returns an SSH credential with a directly entered PEM encoded private key.
+func NewSSHUserPrivateKeyCredentials(id, username, privateKey string) *SSHUserPrivateKeyCredentials {
+	return &SSHUserPrivateKeyCredentials{
+		Scope:    CredentialScopeGlobal,
+		ID:       id,
+		Username: username,
+		PrivateKeySource: &PrivateKeySource{
+			StaplerClass: "com.cloudbees.jenkins.plugins.sshcredentials.impl.BasicSSHUserPrivateKey$DirectEntryPrivateKeySource",
+			PrivateKey:   privateKey,
+		},
+	}
+}
+
+// StringCredentials represents a secret text credential.
+type StringCredentials struct {
+	XMLName xml.Name `xml:"org.jenkinsci.plugins.plaincredentials.impl.StringCredentialsImpl"`
+
+	Scope       CredentialScope `xml:"scope"`
+	ID          string          `xml:"id"`
+	Description string          `xml:"description"`
+	Secret      string          `xml:"secret"`
+}
+
+// FileCredentials represents a secret file credential.
+type FileCredentials struct {
+	XMLName xml.Name `xml:"org.jenkinsci.plugins.plaincredentials.impl.FileCredentialsImpl"`
+
+	Scope       CredentialScope `xml:"scope"`
+	ID          string          `xml:"id"`
+	Description string          `xml:"description"`
+	FileName    string          `xml:"fileName"`
+	// SecretBytes is the base64 encoded content of the file.
+	SecretBytes string `xml:"secretBytes"`
+}
+
+// NewFileCredentials returns a secret file credential with the given file name and content.
+func NewFileCredentials(id, fileName string, content []byte) *FileCredentials {
+	return &FileCredentials{
+		Scope:       CredentialScopeGlobal,
+		ID:          id,
+		FileName:    fileName,
+		SecretBytes: base64.StdEncoding.EncodeToString(content),
+	}
+}
+
+// CertificateCredentials represents a certificate credential, i.e. a PKCS#12 key store.
+type CertificateCredentials struct {
+	XMLName xml.Name `xml:"com.cloudbees.plugins.credentials.impl.CertificateCredentialsImpl"`
+
+	Scope          CredentialScope `xml:"scope"`
+	ID             string          `xml:"id"`
+	Description    string          `xml:"description"`
+	Password       string          `xml:"password"`
+	KeyStoreSource *KeyStoreSource `xml:"keyStoreSource"`
+}
+
+// KeyStoreSource represents the uploaded key store of a certificate credential.
+type KeyStoreSource struct {
+	StaplerClass string `xml:"class,attr"`
+
+	// UploadedKeystoreBytes is the base64 encoded PKCS#12 key store.
+	UploadedKeystoreBytes string `xml:"uploadedKeystoreBytes"`
+}
+
+// NewCertificateCredentials returns a certificate credential with the given PKCS#12 key store and its password.
+func NewCertificateCredentials(id string, keyStore []byte, password string) *CertificateCredentials {
+	return &CertificateCredentials{
+		Scope:    CredentialScopeGlobal,
+		ID:       id,
+		Password: password,
+		KeyStoreSource: &KeyStoreSource{
+			StaplerClass:          "com.cloudbees.plugins.credentials.impl.CertificateCredentialsImpl$UploadedKeyStoreSource",
+			UploadedKeystoreBytes: base64.StdEncoding.EncodeToString(keyStore),
+		},
+	}
+}
+
+// RawCredential represents a credential of a type this package does not model.
+// Its XML is kept verbatim, so it survives an update.
+type RawCredential struct {
+	XMLName  xml.Name
+	Plugin   string `xml:"plugin,attr,omitempty"`
+	InnerXML string `xml:",innerxml"`
+}
+
+// CredentialSummary represents a credential as listed by the JSON API, without its secrets.
+type CredentialSummary struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	TypeName    string `json:"typeName"`
+	Description string `json:"description"`
+	FullName    string `json:"fullName"`
+}
+
+// credentialsListTree limits the listed credentials to the fields of CredentialSummary.
+const credentialsListTree = "credentials[id,displayName,typeName,description,fullName]"
+
+// credentialsListResponse represents a credentials store domain as returned by the JSON API.
+type credentialsListResponse struct {
+	Credentials []CredentialSummary `json:"credentials"`
+}
+
+// decodeCredential decodes a credential config.xml into the type matching its root element.
+// Redacted secrets are decoded as RedactedSecret, as they would be decoded as empty otherwise.
+func decodeCredential(data []byte) (Credential, error) {
+	data = redactedSecretRegexp.ReplaceAll(data, []byte(redactedSecretXML))
+	d := xml.NewDecoder(bytes.NewReader(data))
+
+	var start xml.StartElement
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("credential has no root element")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if el, ok := tok.(xml.StartElement); ok {
+			start = el
+			break
+		}
+	}
+
+	var credential Credential
+	switch start.Name.Local {
+	case "com.cloudbees.plugins.credentials.impl.UsernamePasswordCredentialsImpl":
+		credential = &UsernamePasswordCredentials{}
+	case "com.cloudbees.jenkins.plugins.sshcredentials.impl.BasicSSHUserPrivateKey":
+		credential = &SSHUserPrivateKeyCredentials{}
+	case "org.jenkinsci.plugins.plaincredentials.impl.StringCredentialsImpl":
+		credential = &StringCredentials{}
+	case "org.jenkinsci.plugins.plaincredentials.impl.FileCredentialsImpl":
+		credential = &FileCredentials{}
+	case "com.cloudbees.plugins.credentials.impl.CertificateCredentialsImpl":
+		credential = &CertificateCredentials{}
+	default:
+		credential = &RawCredential{}
+	}
+
+	if err := d.DecodeElement(credential, &start); err != nil {
+		return nil, err
+	}
+
+	return credential, nil
+}
+
+// CredentialsService handles communication with the credentials plugin related methods of the Jenkins API.
+type CredentialsService service
+
+// List returns the credentials of a store domain.
+func (s *CredentialsService) List(ctx context.Context, store CredentialsStore) ([]CredentialSummary, *http.Response, error) {
+	var listResp credentialsListResponse
+	path := fmt.Sprintf(CredentialsListURL, store.URLPath()) + "?" + url.Values{"tree": {credentialsListTree}}.Encode()
+
+	resp, err := s.client.getJSON(ctx, path, &listResp)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return listResp.Credentials, resp, nil
+}
+
+// Create creates a credential in a store domain, e.g. a *UsernamePasswordCredentials.
+func (s *CredentialsService) Create(ctx context.Context, store CredentialsStore, credential Credential) (*http.Response, error) {
+	return s.client.post(ctx, fmt.Sprintf(CredentialsCreateURL, store.URLPath()), credential)
+}
+
+// Get returns a credential of a store domain, decoded into the type matching its class or a *RawCredential.
+// Jenkins does not reveal secrets. Recent versions of the credentials plugin redact them, their value is
+// RedactedSecret then, older versions return them encrypted.
+func (s *CredentialsService) Get(ctx context.Context, store CredentialsStore, id string) (Credential, *http.Response, error) {
+	body, resp, err := s.client.getXML(ctx, fmt.Sprintf(CredentialsConfigURL, store.URLPath(), url.PathEscape(id)))
+	if err != nil {
+		return nil, resp, err
+	}
+
+	credential, err := decodeCredential(body)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return credential, resp, nil
+}
+
+// Update replaces a credential of a store domain. Jenkins would erase a secret sent back as redacted,
+// so an error is returned if the credential still has a RedactedSecret, all secrets must be set then.
+// Secrets returned encrypted by older versions of the credentials plugin can be sent back as they are.
+func (s *CredentialsService) Update(ctx context.Context, store CredentialsStore, id string, credential Credential) (*http.Response, error) {
+	b, err := xml.Marshal(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Contains(b, []byte(redactedSecretXML)) {
+		return nil, fmt.Errorf("credential %s has redacted secrets", id)
+	}
+
+	return s.client.postBody(ctx, fmt.Sprintf(CredentialsConfigURL, store.URLPath(), url.PathEscape(id)), "application/xml", bytes.NewReader(b))
+}
+
+// Delete deletes a credential of a store domain.
+func (s *CredentialsService) Delete(ctx context.Context, store CredentialsStore, id string) (*http.Response, error) {
+	return s.client.post(ctx, fmt.Sprintf(CredentialsDeleteURL, store.URLPath(), url.PathEscape(id)), nil)
+}
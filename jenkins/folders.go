@@ -0,0 +1,97 @@
+// Copyright 2021 The go-jenkins AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jenkins
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// FolderClass is the class name of a CloudBees folder
+	FolderClass = "com.cloudbees.hudson.plugins.folder.Folder"
+
+	// folderListTree limits the folder listing to the fields of Job,
+	// the nested jobs are requested only to tell folders from other items.
+	folderListTree = "jobs[_class,name,fullName,displayName,fullDisplayName,description,url,color,buildable,inQueue,nextBuildNumber,jobs[name]]"
+)
+
+// FoldersService handles communication with the folder related methods of the Jenkins API.
+// Folder names are full names as described by JobPath, e.g. "team/service".
+type FoldersService service
+
+// Create creates a folder including all missing parent folders.
+// Folders that already exist are left untouched. The response for the last folder is returned.
+func (s *FoldersService) Create(ctx context.Context, name string) (*http.Response, error) {
+	var folder JobPath
+	var resp *http.Response
+
+	for _, segment := range JobPath(name).Segments() {
+		folder = folder.Join(segment)
+
+		// Only the last response is returned, so the bodies of the others are closed.
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+
+		_, getResp, err := s.client.Jobs.Get(ctx, string(folder))
+		if err == nil {
+			resp = getResp
+			continue
+		}
+		if getResp == nil || getResp.StatusCode != http.StatusNotFound {
+			return getResp, err
+		}
+		_ = getResp.Body.Close()
+
+		resp, err = s.client.postForm(ctx, fmt.Sprintf(JobsCreateURL, folder.Parent().URLPath()), url.Values{
+			"name": {folder.Name()},
+			"mode": {FolderClass},
+		})
+		if err != nil {
+			return resp, err
+		}
+	}
+
+	return resp, nil
+}
+
+// List returns the items of a folder, an empty name lists the top level items.
+// If recursive is set, the items of all nested folders are included as well.
+func (s *FoldersService) List(ctx context.Context, name string, recursive bool) ([]Job, *http.Response, error) {
+	var folder Job
+	path := fmt.Sprintf(JobsGetURL, JobPath(name).URLPath()) + "?" + url.Values{"tree": {folderListTree}}.Encode()
+
+	resp, err := s.client.getJSON(ctx, path, &folder)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var jobs []Job
+	for _, job := range folder.Jobs {
+		jobs = append(jobs, job)
+
+		// Only item groups report nested jobs, even when they are empty.
+		if !recursive || job.Jobs == nil {
+			continue
+		}
+
+		nested, resp, err := s.List(ctx, job.FullName, true)
+		if err != nil {
+			return nil, resp, err
+		}
+		jobs = append(jobs, nested...)
+	}
+
+	return jobs, resp, nil
+}
+
+// Delete deletes a folder. Jenkins deletes all items of the folder recursively.
+func (s *FoldersService) Delete(ctx context.Context, name string) (*http.Response, error) {
+	return s.client.Jobs.Delete(ctx, name)
+}
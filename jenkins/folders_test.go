@@ -0,0 +1,146 @@
+package jenkins
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// bodyTracker is a transport counting the response bodies that were not closed.
+type bodyTracker struct {
+	open int
+}
+
+func (t *bodyTracker) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.open++
+	resp.Body = &trackedBody{ReadCloser: resp.Body, tracker: t}
+
+	return resp, nil
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	tracker *bodyTracker
+	closed  bool
+}
+
+func (b *trackedBody) Close() error {
+	if !b.closed {
+		b.closed = true
+		b.tracker.open--
+	}
+
+	return b.ReadCloser.Close()
+}
+
+func (s *Suite) TestFoldersServiceCreate() {
+	s.newMux()
+	tracker := &bodyTracker{}
+	client, err := NewClient(WithBaseURL(s.server.URL), WithClient(&http.Client{Transport: tracker}))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc("/job/team/api/json", func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(`{"_class":"com.cloudbees.hudson.plugins.folder.Folder","name":"team"}`))
+		s.NoError(err)
+	})
+
+	var created []string
+	s.mux.HandleFunc("/job/team/createItem", func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal(FolderClass, r.FormValue("mode"))
+		created = append(created, r.FormValue("name"))
+		s.mux.HandleFunc("/job/team/job/service/api/json", func(w http.ResponseWriter, r *http.Request) {})
+	})
+	s.mux.HandleFunc("/job/team/job/service/createItem", func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		created = append(created, r.FormValue("name"))
+	})
+
+	resp, err := client.Folders.Create(context.Background(), "team/service/deploy")
+	s.NoError(err)
+	s.Equal([]string{"service", "deploy"}, created)
+
+	// Only the body of the returned response is left open.
+	s.Equal(1, tracker.open)
+	s.NoError(resp.Body.Close())
+	s.Equal(0, tracker.open)
+}
+
+func (s *Suite) TestFoldersServiceCreateError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc("/job/team/api/json", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	resp, err := client.Folders.Create(context.Background(), "team")
+	s.Error(err)
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+}
+
+func (s *Suite) TestFoldersServiceList() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc("/api/json", func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		s.Equal(folderListTree, r.URL.Query().Get("tree"))
+		_, err := w.Write([]byte(`{"jobs":[
+			{"_class":"com.cloudbees.hudson.plugins.folder.Folder","name":"team","fullName":"team","jobs":[{"name":"deploy"}]},
+			{"_class":"hudson.model.FreeStyleProject","name":"build","fullName":"build"}
+		]}`))
+		s.NoError(err)
+	})
+	s.mux.HandleFunc("/job/team/api/json", func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(`{"jobs":[
+			{"_class":"hudson.model.FreeStyleProject","name":"deploy","fullName":"team/deploy"}
+		]}`))
+		s.NoError(err)
+	})
+
+	jobs, _, err := client.Folders.List(context.Background(), "", false)
+	s.NoError(err)
+	s.Len(jobs, 2)
+
+	jobs, _, err = client.Folders.List(context.Background(), "", true)
+	s.NoError(err)
+	s.Len(jobs, 3)
+	s.Equal("team/deploy", jobs[1].FullName)
+	s.Equal("build", jobs[2].FullName)
+}
+
+func (s *Suite) TestFoldersServiceListError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	_, _, err = client.Folders.List(context.Background(), "team", true)
+	s.Error(err)
+}
+
+func (s *Suite) TestFoldersServiceDelete() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc("/job/team/job/service/doDelete", func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+	})
+
+	_, err = client.Folders.Delete(context.Background(), "team/service")
+	s.NoError(err)
+}
@@ -11,12 +11,14 @@ import (
 	"net/url"
 	"reflect"
 	"strings"
+	"time"
 )
 
 const (
-	crumbURL        = "/crumbIssuer/api/json"
-	defaultBaseURL  = "http://127.0.0.1:8080"
-	defaultUserName = "admin"
+	crumbURL            = "/crumbIssuer/api/json"
+	defaultBaseURL      = "http://127.0.0.1:8080"
+	defaultUserName     = "admin"
+	defaultPollInterval = time.Second
 )
 
 type Crumbs struct {
@@ -43,8 +45,18 @@ type Client struct {
 	userAgent  string
 	crumbs     *Crumbs
 
-	common service
-	Nodes  *NodesService
+	pollInterval time.Duration
+
+	common      service
+	Nodes       *NodesService
+	Jobs        *JobsService
+	Folders     *FoldersService
+	Builds      *BuildsService
+	Queue       *QueueService
+	Pipelines   *PipelinesService
+	Multibranch *MultibranchService
+	Clouds      *CloudsService
+	Credentials *CredentialsService
 }
 
 type service struct {
@@ -93,11 +105,24 @@ func WithUserToken(userName, apiToken string) ClientOption {
 	}
 }
 
+// WithPollInterval sets how often the client polls Jenkins while waiting,
+// e.g. for a queued build to start
+func WithPollInterval(interval time.Duration) ClientOption {
+	return func(c *Client) error {
+		if interval <= 0 {
+			return fmt.Errorf("poll interval must be positive")
+		}
+		c.pollInterval = interval
+		return nil
+	}
+}
+
 // NewClient returns a new Jenkins API client
 func NewClient(opts ...ClientOption) (*Client, error) {
 	c := &Client{
-		baseURL:  defaultBaseURL,
-		userName: defaultUserName,
+		baseURL:      defaultBaseURL,
+		userName:     defaultUserName,
+		pollInterval: defaultPollInterval,
 	}
 
 	for _, opt := range opts {
@@ -123,10 +148,37 @@ func NewClient(opts ...ClientOption) (*Client, error) {
 
 	c.common.client = c
 	c.Nodes = (*NodesService)(&c.common)
+	c.Jobs = (*JobsService)(&c.common)
+	c.Folders = (*FoldersService)(&c.common)
+	c.Builds = (*BuildsService)(&c.common)
+	c.Queue = (*QueueService)(&c.common)
+	c.Pipelines = (*PipelinesService)(&c.common)
+	c.Multibranch = (*MultibranchService)(&c.common)
+	c.Clouds = (*CloudsService)(&c.common)
+	c.Credentials = (*CredentialsService)(&c.common)
 
 	return c, nil
 }
 
+// poll calls done every poll interval until it reports true, fails or the context is done.
+func (c *Client) poll(ctx context.Context, done func() (bool, error)) error {
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		ok, err := done()
+		if err != nil || ok {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
 func (c *Client) setCrumbs(ctx context.Context) error {
 	resp, err := c.get(ctx, crumbURL)
 	if err != nil {
@@ -144,11 +196,18 @@ func (c *Client) setCrumbs(ctx context.Context) error {
 }
 
 func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	// url.JoinPath escapes "?", so the query string is appended afterwards.
+	path, query, _ := strings.Cut(path, "?")
+
 	u, err := url.JoinPath(c.baseURL, path)
 	if err != nil {
 		return nil, err
 	}
 
+	if query != "" {
+		u += "?" + query
+	}
+
 	req, err := http.NewRequestWithContext(ctx, method, u, body)
 	if err != nil {
 		return nil, err
@@ -177,24 +236,123 @@ func (c *Client) newFormRequest(ctx context.Context, path string, values url.Val
 	return req, nil
 }
 
-func (c *Client) get(ctx context.Context, path string) (*http.Response, error) {
+// requestOption modifies a request before it is sent.
+type requestOption func(req *http.Request)
+
+// ifModifiedSince makes a GET request conditional, Jenkins responds with 304 Not Modified
+// if the resource was not modified since t. A zero t leaves the request unconditional.
+func ifModifiedSince(t time.Time) requestOption {
+	return func(req *http.Request) {
+		if !t.IsZero() {
+			req.Header.Set("If-Modified-Since", t.UTC().Format(http.TimeFormat))
+		}
+	}
+}
+
+// acceptLanguage requests responses in the given language, e.g. "en" for messages that are parsed.
+func acceptLanguage(lang string) requestOption {
+	return func(req *http.Request) {
+		req.Header.Set("Accept-Language", lang)
+	}
+}
+
+// get sends a GET request. A 304 Not Modified response to a conditional request is no error.
+func (c *Client) get(ctx context.Context, path string, opts ...requestOption) (*http.Response, error) {
 	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
 	if err != nil {
 		return nil, err
 	}
 
+	for _, opt := range opts {
+		opt(req)
+	}
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode > 299 {
+	if resp.StatusCode > 299 && resp.StatusCode != http.StatusNotModified {
 		return resp, fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
 	}
 
 	return resp, nil
 }
 
+// getJSON sends a GET request and decodes the JSON response body into v.
+func (c *Client) getJSON(ctx context.Context, path string, v interface{}, opts ...requestOption) (*http.Response, error) {
+	resp, err := c.get(ctx, path, opts...)
+	if err != nil {
+		return resp, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, json.Unmarshal(body, v)
+}
+
+// getXML sends a GET request and returns the XML response body.
+// The XML declaration is downgraded to version 1.0, see xml10.
+func (c *Client) getXML(ctx context.Context, path string) ([]byte, *http.Response, error) {
+	body, resp, err := c.getBody(ctx, path)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return xml10(body), resp, nil
+}
+
+// download sends a GET request and writes the response body to w.
+func (c *Client) download(ctx context.Context, path string, w io.Writer, opts ...requestOption) (*http.Response, error) {
+	resp, err := c.get(ctx, path, opts...)
+	if err != nil {
+		return resp, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	_, err = io.Copy(w, resp.Body)
+
+	return resp, err
+}
+
+// getBody sends a GET request and returns the whole response body.
+func (c *Client) getBody(ctx context.Context, path string) ([]byte, *http.Response, error) {
+	resp, err := c.get(ctx, path)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return body, resp, nil
+}
+
+// xml10 rewrites the XML 1.1 declaration Jenkins emits to XML 1.0.
+// Golang cannot unmarshal xml 1.1 documents.
+// Jenkins XMLs are basically 1.0 documents.
+func xml10(body []byte) []byte {
+	s := strings.Replace(string(body), "xml version=\"1.1\"", "xml version=\"1.0\"", 1)
+	s = strings.Replace(s, "xml version='1.1'", "xml version='1.0'", 1)
+	return []byte(s)
+}
+
 func convertBodyStruct(body interface{}) url.Values {
 	values := make(url.Values)
 	v := reflect.ValueOf(body).Elem()
@@ -217,7 +375,10 @@ func (c *Client) postForm(ctx context.Context, path string, body interface{}) (*
 		return nil, err
 	}
 
-	values := convertBodyStruct(body)
+	values, ok := body.(url.Values)
+	if !ok {
+		values = convertBodyStruct(body)
+	}
 
 	req, err := c.newFormRequest(ctx, path, values)
 	if err != nil {
@@ -237,21 +398,27 @@ func (c *Client) postForm(ctx context.Context, path string, body interface{}) (*
 }
 
 func (c *Client) post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
-	if err := c.setCrumbs(ctx); err != nil {
+	b, err := xml.Marshal(body)
+	if err != nil {
 		return nil, err
 	}
 
-	b, err := xml.Marshal(body)
-	if err != nil {
+	return c.postBody(ctx, path, "application/xml", strings.NewReader(string(b)))
+}
+
+// postBody sends a POST request with the given content type and body.
+// Crumbs are fetched and attached to the request.
+func (c *Client) postBody(ctx context.Context, path, contentType string, body io.Reader) (*http.Response, error) {
+	if err := c.setCrumbs(ctx); err != nil {
 		return nil, err
 	}
 
-	req, err := c.newRequest(ctx, http.MethodPost, path, strings.NewReader(string(b)))
+	req, err := c.newRequest(ctx, http.MethodPost, path, body)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Content-Type", contentType)
 
 	if c.crumbs != nil {
 		req.Header.Add(c.crumbs.RequestField, c.crumbs.Value)
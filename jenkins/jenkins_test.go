@@ -399,3 +399,41 @@ func (s *Suite) TestClientPostNotOK() {
 	_, err = client.post(context.Background(), "test", nil)
 	s.Error(err)
 }
+
+func (s *Suite) TestClientNewRequestQuery() {
+	client, err := NewClient(WithBaseURL("http://jenkins"))
+	s.NoError(err)
+
+	req, err := client.newRequest(context.Background(), "GET", "/a b/api?x=1&y=2", nil)
+	s.NoError(err)
+	s.Equal("http://jenkins/a%20b/api?x=1&y=2", req.URL.String())
+}
+
+func (s *Suite) TestClientPostFormValues() {
+	s.newMux()
+	s.addCrumbsHandle()
+	s.mux.HandleFunc("/post", func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("B", r.FormValue("a"))
+	})
+
+	client, err := NewClient(WithBaseURL(s.server.URL))
+	s.NoError(err)
+
+	_, err = client.postForm(context.Background(), "post", url.Values{"a": {"B"}})
+	s.NoError(err)
+}
+
+func (s *Suite) TestXML10() {
+	s.Equal(`<?xml version="1.0"?>`, string(xml10([]byte(`<?xml version="1.1"?>`))))
+	s.Equal(`<?xml version='1.0'?>`, string(xml10([]byte(`<?xml version='1.1'?>`))))
+}
+
+func (s *Suite) TestNewClientWithPollInterval() {
+	client, err := NewClient(WithPollInterval(time.Millisecond))
+	s.NoError(err)
+	s.Equal(time.Millisecond, client.pollInterval)
+
+	_, err = NewClient(WithPollInterval(0))
+	s.Error(err)
+}
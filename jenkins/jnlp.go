@@ -0,0 +1,187 @@
+// Copyright 2021 The go-jenkins AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jenkins
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// NodesJNLPURL is the URL to get the JNLP file of an inbound agent
+	NodesJNLPURL = "/computer/%s/jenkins-agent.jnlp"
+	// NodesLegacyJNLPURL is the URL to get the JNLP file of an inbound agent on older Jenkins versions
+	NodesLegacyJNLPURL = "/computer/%s/slave-agent.jnlp"
+	// NodesAgentJarURL is the URL to download the agent.jar inbound agents are run with
+	NodesAgentJarURL = "/jnlpJars/agent.jar"
+)
+
+// jnlpFile represents the parts of a JNLP file needed to connect an inbound agent.
+type jnlpFile struct {
+	Codebase  string   `xml:"codebase,attr"`
+	Arguments []string `xml:"application-desc>argument"`
+}
+
+// JNLPConnection represents the connection details of an inbound agent as listed in its JNLP file.
+type JNLPConnection struct {
+	// Secret authenticates the agent, it is unique for every node.
+	Secret string
+	// Name is the name of the node.
+	Name string
+	// URL is the Jenkins URL the agent connects to.
+	URL string
+	// WorkDir is the work directory of the agent, empty if disabled.
+	WorkDir string
+	// InternalDir is the directory for remoting caches and logs relative to WorkDir.
+	InternalDir string
+	// Tunnel is the "host:port" the agent connects to instead of the advertised TCP port.
+	Tunnel string
+	// WebSocket is set if the agent connects over WebSocket instead of the TCP port.
+	WebSocket bool
+	// FailIfWorkDirIsMissing is set if the agent must not create a missing work directory.
+	FailIfWorkDirIsMissing bool
+	// Args are all arguments of the JNLP file, including the ones above.
+	Args []string
+}
+
+// parseJNLP parses a JNLP file. The arguments start with the secret and the node name followed by options.
+func parseJNLP(data []byte) (*JNLPConnection, error) {
+	var file jnlpFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	if len(file.Arguments) < 2 {
+		return nil, fmt.Errorf("JNLP file lacks the agent secret and name")
+	}
+
+	conn := &JNLPConnection{
+		Secret: file.Arguments[0],
+		Name:   file.Arguments[1],
+		Args:   file.Arguments,
+	}
+
+	options := file.Arguments[2:]
+	for i := 0; i < len(options); i++ {
+		var value *string
+		switch options[i] {
+		case "-url":
+			value = &conn.URL
+		case "-workDir":
+			value = &conn.WorkDir
+		case "-internalDir":
+			value = &conn.InternalDir
+		case "-tunnel":
+			value = &conn.Tunnel
+		case "-webSocket":
+			conn.WebSocket = true
+		case "-failIfWorkDirIsMissing":
+			conn.FailIfWorkDirIsMissing = true
+		}
+
+		if value != nil && i+1 < len(options) {
+			i++
+			*value = options[i]
+		}
+	}
+
+	// Older Jenkins versions do not pass the URL, the codebase is the URL of the node then.
+	if conn.URL == "" {
+		if i := strings.Index(file.Codebase, "computer/"); i >= 0 {
+			conn.URL = file.Codebase[:i]
+		}
+	}
+
+	return conn, nil
+}
+
+// Command returns the command line starting the agent with the agent.jar at the given path,
+// e.g. "java -jar agent.jar -url http://jenkins/ -secret ... -name agent-1 -workDir /home/jenkins".
+func (c *JNLPConnection) Command(agentJar string) []string {
+	command := []string{"java", "-jar", agentJar, "-url", c.URL, "-secret", c.Secret, "-name", c.Name}
+
+	if c.WorkDir != "" {
+		command = append(command, "-workDir", c.WorkDir)
+	}
+	if c.InternalDir != "" {
+		command = append(command, "-internalDir", c.InternalDir)
+	}
+	if c.FailIfWorkDirIsMissing {
+		command = append(command, "-failIfWorkDirIsMissing")
+	}
+	if c.Tunnel != "" {
+		command = append(command, "-tunnel", c.Tunnel)
+	}
+	if c.WebSocket {
+		command = append(command, "-webSocket")
+	}
+
+	return command
+}
+
+// CommandLine returns the command of Command quoted for a POSIX shell.
+func (c *JNLPConnection) CommandLine(agentJar string) string {
+	command := c.Command(agentJar)
+	for i, arg := range command {
+		command[i] = shellQuote(arg)
+	}
+
+	return strings.Join(command, " ")
+}
+
+// shellQuote quotes s for a POSIX shell unless it consists of safe characters only.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@%+,") == "" {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// GetJNLPConnection returns the connection details of an inbound agent, i.e. a node with a JNLPLauncher.
+// Jenkins versions not serving jenkins-agent.jnlp are asked for slave-agent.jnlp instead.
+func (s *NodesService) GetJNLPConnection(ctx context.Context, name string) (*JNLPConnection, *http.Response, error) {
+	data, resp, err := s.client.getXML(ctx, fmt.Sprintf(NodesJNLPURL, name))
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		// The body of the error response is drained, so the connection is reused for the retry.
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		data, resp, err = s.client.getXML(ctx, fmt.Sprintf(NodesLegacyJNLPURL, name))
+	}
+	if err != nil {
+		return nil, resp, err
+	}
+
+	conn, err := parseJNLP(data)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return conn, resp, nil
+}
+
+// DownloadAgentJar writes the agent.jar matching the version of Jenkins to w.
+func (s *NodesService) DownloadAgentJar(ctx context.Context, w io.Writer) (*http.Response, error) {
+	return s.client.download(ctx, NodesAgentJarURL, w)
+}
+
+// DownloadAgentJarIfModified writes the agent.jar matching the version of Jenkins to w unless it was not
+// modified since the given time, e.g. the Last-Modified header of an earlier download, and reports
+// whether it was written. A zero time downloads agent.jar unconditionally.
+func (s *NodesService) DownloadAgentJarIfModified(ctx context.Context, since time.Time, w io.Writer) (bool, *http.Response, error) {
+	resp, err := s.client.download(ctx, NodesAgentJarURL, w, ifModifiedSince(since))
+	if err != nil {
+		return false, resp, err
+	}
+
+	return resp.StatusCode != http.StatusNotModified, resp, nil
+}
@@ -0,0 +1,181 @@
+package jenkins
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const agentJNLP = `<jnlp codebase="http://jenkins.example.com/computer/agent-1/" spec="1.0+">
+  <information>
+    <title>Agent for agent-1</title>
+    <vendor>Jenkins project</vendor>
+  </information>
+  <security><all-permissions/></security>
+  <resources>
+    <j2se version="1.8+"/>
+    <jar href="http://jenkins.example.com/jnlpJars/remoting.jar"/>
+  </resources>
+  <application-desc main-class="hudson.remoting.jnlp.Main">
+    <argument>0123456789abcdef</argument>
+    <argument>agent-1</argument>
+    <argument>-workDir</argument>
+    <argument>/home/jenkins/agent work</argument>
+    <argument>-internalDir</argument>
+    <argument>remoting</argument>
+    <argument>-url</argument>
+    <argument>http://jenkins.example.com/</argument>
+    <argument>-webSocket</argument>
+  </application-desc>
+</jnlp>`
+
+func (s *Suite) TestNodesServiceGetJNLPConnection() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(NodesJNLPURL, "agent-1"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(agentJNLP))
+		s.NoError(err)
+	})
+
+	conn, _, err := client.Nodes.GetJNLPConnection(context.Background(), "agent-1")
+	s.NoError(err)
+	s.Equal("0123456789abcdef", conn.Secret)
+	s.Equal("agent-1", conn.Name)
+	s.Equal("http://jenkins.example.com/", conn.URL)
+	s.Equal("/home/jenkins/agent work", conn.WorkDir)
+	s.Equal("remoting", conn.InternalDir)
+	s.True(conn.WebSocket)
+	s.False(conn.FailIfWorkDirIsMissing)
+	s.Len(conn.Args, 9)
+
+	s.Equal([]string{
+		"java", "-jar", "agent.jar",
+		"-url", "http://jenkins.example.com/",
+		"-secret", "0123456789abcdef",
+		"-name", "agent-1",
+		"-workDir", "/home/jenkins/agent work",
+		"-internalDir", "remoting",
+		"-webSocket",
+	}, conn.Command("agent.jar"))
+	s.Equal("java -jar /opt/agent.jar -url http://jenkins.example.com/ -secret 0123456789abcdef -name agent-1 "+
+		"-workDir '/home/jenkins/agent work' -internalDir remoting -webSocket", conn.CommandLine("/opt/agent.jar"))
+}
+
+func (s *Suite) TestNodesServiceGetJNLPConnectionLegacy() {
+	s.newMux()
+	tracker := &bodyTracker{}
+	client, err := NewClient(WithBaseURL(s.server.URL), WithClient(&http.Client{Transport: tracker}))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(NodesLegacyJNLPURL, "agent-1"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(`<?xml version="1.1" encoding="UTF-8"?>
+<jnlp codebase="http://jenkins.example.com/jenkins/computer/agent-1/" spec="1.0+">
+  <application-desc main-class="hudson.remoting.jnlp.Main">
+    <argument>secret</argument>
+    <argument>agent-1</argument>
+    <argument>-tunnel</argument>
+    <argument>proxy:50000</argument>
+    <argument>-failIfWorkDirIsMissing</argument>
+  </application-desc>
+</jnlp>`))
+		s.NoError(err)
+	})
+
+	conn, _, err := client.Nodes.GetJNLPConnection(context.Background(), "agent-1")
+	s.NoError(err)
+	s.Equal("http://jenkins.example.com/jenkins/", conn.URL)
+	s.Equal("proxy:50000", conn.Tunnel)
+	s.True(conn.FailIfWorkDirIsMissing)
+	s.Equal("java -jar agent.jar -url http://jenkins.example.com/jenkins/ -secret secret -name agent-1 "+
+		"-failIfWorkDirIsMissing -tunnel proxy:50000", conn.CommandLine("agent.jar"))
+
+	// The body of the response for the missing JNLP file is closed before the retry.
+	s.Equal(0, tracker.open)
+}
+
+func (s *Suite) TestNodesServiceGetJNLPConnectionError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(NodesJNLPURL, "ssh"), func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`<jnlp><application-desc/></jnlp>`))
+		s.NoError(err)
+	})
+	s.mux.HandleFunc(fmt.Sprintf(NodesJNLPURL, "forbidden"), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	_, resp, err := client.Nodes.GetJNLPConnection(context.Background(), "missing")
+	s.Error(err)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+
+	_, _, err = client.Nodes.GetJNLPConnection(context.Background(), "ssh")
+	s.Error(err)
+
+	_, resp, err = client.Nodes.GetJNLPConnection(context.Background(), "forbidden")
+	s.Error(err)
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+}
+
+func (s *Suite) TestShellQuote() {
+	s.Equal("agent.jar", shellQuote("agent.jar"))
+	s.Equal("''", shellQuote(""))
+	s.Equal(`'it'\''s'`, shellQuote("it's"))
+	s.Equal("'$HOME'", shellQuote("$HOME"))
+}
+
+func (s *Suite) TestNodesServiceDownloadAgentJar() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(NodesAgentJarURL, func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte("PK agent"))
+		s.NoError(err)
+	})
+
+	var jar bytes.Buffer
+	_, err = client.Nodes.DownloadAgentJar(context.Background(), &jar)
+	s.NoError(err)
+	s.Equal("PK agent", jar.String())
+}
+
+func (s *Suite) TestNodesServiceDownloadAgentJarIfModified() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	modified := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	s.mux.HandleFunc(NodesAgentJarURL, func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		http.ServeContent(w, r, "agent.jar", modified, strings.NewReader("PK agent"))
+	})
+
+	var jar bytes.Buffer
+	written, resp, err := client.Nodes.DownloadAgentJarIfModified(context.Background(), time.Time{}, &jar)
+	s.NoError(err)
+	s.True(written)
+	s.Equal("PK agent", jar.String())
+	s.Equal(modified.Format(http.TimeFormat), resp.Header.Get("Last-Modified"))
+
+	jar.Reset()
+	written, resp, err = client.Nodes.DownloadAgentJarIfModified(context.Background(), modified, &jar)
+	s.NoError(err)
+	s.False(written)
+	s.Equal(http.StatusNotModified, resp.StatusCode)
+	s.Empty(jar.String())
+
+	written, _, err = client.Nodes.DownloadAgentJarIfModified(context.Background(), modified.Add(-time.Hour), &jar)
+	s.NoError(err)
+	s.True(written)
+	s.Equal("PK agent", jar.String())
+}
@@ -0,0 +1,365 @@
+// Copyright 2021 The go-jenkins AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jenkins
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// The job URLs below are formatted with JobPath.URLPath, e.g. "/job/team/job/deploy".
+const (
+	// JobsCreateURL is the URL to create a new job in a folder
+	JobsCreateURL = "%s/createItem"
+	// JobsGetURL is the URL to get a job
+	JobsGetURL = "%s/api/json"
+	// JobsConfigURL is the URL to get or update a job configuration
+	JobsConfigURL = "%s/config.xml"
+	// JobsDeleteURL is the URL to delete a job
+	JobsDeleteURL = "%s/doDelete"
+	// JobsRenameURL is the URL to rename a job
+	JobsRenameURL = "%s/confirmRename"
+)
+
+// JobPath is the slash-separated full name of a job, e.g. "team/service/deploy".
+// Jobs nested in folders are addressed by Jenkins as "/job/team/job/service/job/deploy".
+type JobPath string
+
+// ParseJobURL converts a Jenkins job URL or URL path, e.g. "http://jenkins/job/team/job/deploy/",
+// to a JobPath. Any context path before the first "job" segment is ignored.
+func ParseJobURL(rawURL string) (JobPath, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	segments := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+	for len(segments) > 0 && segments[0] != "job" {
+		segments = segments[1:]
+	}
+
+	if len(segments) == 0 || len(segments)%2 != 0 {
+		return "", fmt.Errorf("not a job URL: %s", rawURL)
+	}
+
+	names := make([]string, 0, len(segments)/2)
+	for i := 0; i < len(segments); i += 2 {
+		if segments[i] != "job" || segments[i+1] == "" {
+			return "", fmt.Errorf("not a job URL: %s", rawURL)
+		}
+
+		name, err := url.PathUnescape(segments[i+1])
+		if err != nil {
+			return "", err
+		}
+		names = append(names, name)
+	}
+
+	return JobPath(strings.Join(names, "/")), nil
+}
+
+// Segments returns the names of the folders and the job the path consists of.
+func (p JobPath) Segments() []string {
+	var segments []string
+	for _, segment := range strings.Split(string(p), "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	return segments
+}
+
+// Name returns the last segment of the path, i.e. the short name of the job.
+func (p JobPath) Name() string {
+	segments := p.Segments()
+	if len(segments) == 0 {
+		return ""
+	}
+
+	return segments[len(segments)-1]
+}
+
+// Parent returns the path of the folder containing the job.
+// Jobs at the top level have an empty parent.
+func (p JobPath) Parent() JobPath {
+	segments := p.Segments()
+	if len(segments) == 0 {
+		return ""
+	}
+
+	return JobPath(strings.Join(segments[:len(segments)-1], "/"))
+}
+
+// Join returns the path of the named item inside the folder p.
+func (p JobPath) Join(name string) JobPath {
+	return JobPath(strings.Join(append(p.Segments(), name), "/"))
+}
+
+// URLPath returns the escaped Jenkins URL path of the job, e.g. "/job/team/job/deploy".
+// The root path has an empty URL path.
+func (p JobPath) URLPath() string {
+	var b strings.Builder
+	for _, segment := range p.Segments() {
+		b.WriteString("/job/")
+		b.WriteString(url.PathEscape(segment))
+	}
+
+	return b.String()
+}
+
+// Job represents a Jenkins job as returned by the JSON API.
+type Job struct {
+	Class           string `json:"_class"`
+	Name            string `json:"name"`
+	FullName        string `json:"fullName"`
+	DisplayName     string `json:"displayName"`
+	FullDisplayName string `json:"fullDisplayName"`
+	Description     string `json:"description"`
+	URL             string `json:"url"`
+	Color           string `json:"color"`
+	Buildable       bool   `json:"buildable"`
+	InQueue         bool   `json:"inQueue"`
+	NextBuildNumber int    `json:"nextBuildNumber"`
+	Jobs            []Job  `json:"jobs"`
+
+	Builds                []BuildRef `json:"builds"`
+	FirstBuild            *BuildRef  `json:"firstBuild"`
+	LastBuild             *BuildRef  `json:"lastBuild"`
+	LastCompletedBuild    *BuildRef  `json:"lastCompletedBuild"`
+	LastFailedBuild       *BuildRef  `json:"lastFailedBuild"`
+	LastStableBuild       *BuildRef  `json:"lastStableBuild"`
+	LastSuccessfulBuild   *BuildRef  `json:"lastSuccessfulBuild"`
+	LastUnstableBuild     *BuildRef  `json:"lastUnstableBuild"`
+	LastUnsuccessfulBuild *BuildRef  `json:"lastUnsuccessfulBuild"`
+}
+
+// RawElement represents an XML element of a config.xml this package does not model.
+// It is kept verbatim, so it survives an update.
+type RawElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	InnerXML string     `xml:",innerxml"`
+}
+
+// FreestyleProject represents the config.xml of a Jenkins freestyle job.
+// Elements it does not model, e.g. the SCM, triggers, publishers and properties,
+// are kept in Unknown, so a decoded job can be updated without losing them.
+type FreestyleProject struct {
+	XMLName xml.Name `xml:"project"`
+
+	Description                      string    `xml:"description"`
+	KeepDependencies                 bool      `xml:"keepDependencies"`
+	CanRoam                          bool      `xml:"canRoam"`
+	AssignedNode                     string    `xml:"assignedNode,omitempty"`
+	Disabled                         bool      `xml:"disabled"`
+	BlockBuildWhenDownstreamBuilding bool      `xml:"blockBuildWhenDownstreamBuilding"`
+	BlockBuildWhenUpstreamBuilding   bool      `xml:"blockBuildWhenUpstreamBuilding"`
+	ConcurrentBuild                  bool      `xml:"concurrentBuild"`
+	Builders                         *Builders `xml:"builders"`
+
+	Unknown []RawElement `xml:",any"`
+}
+
+// NewFreestyleProject returns a freestyle job running the given shell commands.
+// The job can roam between nodes unless AssignedNode is set.
+func NewFreestyleProject(description string, commands ...string) *FreestyleProject {
+	builders := &Builders{}
+	for _, command := range commands {
+		builders.Shell = append(builders.Shell, ShellBuilder{Command: command})
+	}
+
+	return &FreestyleProject{
+		Description: description,
+		CanRoam:     true,
+		Builders:    builders,
+	}
+}
+
+// shellBuilderName is the element name of an "Execute shell" build step.
+const shellBuilderName = "hudson.tasks.Shell"
+
+// Builders represents the build steps of a freestyle job.
+// Steps other than shell steps are kept in Unknown. The order of decoded steps is kept,
+// added steps are appended, shell steps first.
+type Builders struct {
+	Shell   []ShellBuilder
+	Unknown []RawElement
+
+	// steps records for every decoded step in order whether it is a shell step.
+	steps []bool
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (b Builders) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	shell, unknown := 0, 0
+	encodeShell := func() error {
+		shell++
+		return e.EncodeElement(b.Shell[shell-1], xml.StartElement{Name: xml.Name{Local: shellBuilderName}})
+	}
+	encodeUnknown := func() error {
+		unknown++
+		return e.Encode(b.Unknown[unknown-1])
+	}
+
+	for _, isShell := range b.steps {
+		var err error
+		switch {
+		case isShell && shell < len(b.Shell):
+			err = encodeShell()
+		case !isShell && unknown < len(b.Unknown):
+			err = encodeUnknown()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for shell < len(b.Shell) {
+		if err := encodeShell(); err != nil {
+			return err
+		}
+	}
+
+	for unknown < len(b.Unknown) {
+		if err := encodeUnknown(); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (b *Builders) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*b = Builders{}
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == shellBuilderName {
+				var shell ShellBuilder
+				if err := d.DecodeElement(&shell, &t); err != nil {
+					return err
+				}
+				b.Shell = append(b.Shell, shell)
+				b.steps = append(b.steps, true)
+				continue
+			}
+
+			var raw RawElement
+			if err := d.DecodeElement(&raw, &t); err != nil {
+				return err
+			}
+			b.Unknown = append(b.Unknown, raw)
+			b.steps = append(b.steps, false)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// ShellBuilder represents an "Execute shell" build step.
+type ShellBuilder struct {
+	Command string `xml:"command"`
+
+	Unknown []RawElement `xml:",any"`
+}
+
+// JobsService handles communication with the job related methods of the Jenkins API.
+// Job names are full names as described by JobPath, e.g. "team/service/deploy".
+type JobsService service
+
+// Create creates a new Jenkins job from a typed configuration, e.g. FreestyleProject.
+func (s *JobsService) Create(ctx context.Context, name string, config interface{}) (*http.Response, error) {
+	b, err := xml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.CreateXML(ctx, name, b)
+}
+
+// CreateXML creates a new Jenkins job from a raw config.xml document.
+// The folder containing the job must already exist.
+func (s *JobsService) CreateXML(ctx context.Context, name string, config []byte) (*http.Response, error) {
+	jobPath := JobPath(name)
+	path := fmt.Sprintf(JobsCreateURL, jobPath.Parent().URLPath()) + "?" + url.Values{"name": {jobPath.Name()}}.Encode()
+
+	return s.client.postBody(ctx, path, "application/xml", bytes.NewReader(config))
+}
+
+// Get returns a Jenkins job.
+func (s *JobsService) Get(ctx context.Context, name string) (*Job, *http.Response, error) {
+	var job Job
+	resp, err := s.client.getJSON(ctx, fmt.Sprintf(JobsGetURL, JobPath(name).URLPath()), &job)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &job, resp, nil
+}
+
+// GetConfig decodes the config.xml of a Jenkins job into config, e.g. FreestyleProject.
+func (s *JobsService) GetConfig(ctx context.Context, name string, config interface{}) (*http.Response, error) {
+	body, resp, err := s.GetConfigXML(ctx, name)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, xml.Unmarshal(body, config)
+}
+
+// GetConfigXML returns the raw config.xml of a Jenkins job.
+func (s *JobsService) GetConfigXML(ctx context.Context, name string) ([]byte, *http.Response, error) {
+	return s.client.getXML(ctx, fmt.Sprintf(JobsConfigURL, JobPath(name).URLPath()))
+}
+
+// UpdateConfig replaces the config.xml of a Jenkins job with a typed configuration.
+func (s *JobsService) UpdateConfig(ctx context.Context, name string, config interface{}) (*http.Response, error) {
+	return s.client.post(ctx, fmt.Sprintf(JobsConfigURL, JobPath(name).URLPath()), config)
+}
+
+// UpdateConfigXML replaces the config.xml of a Jenkins job with a raw document.
+func (s *JobsService) UpdateConfigXML(ctx context.Context, name string, config []byte) (*http.Response, error) {
+	return s.client.postBody(ctx, fmt.Sprintf(JobsConfigURL, JobPath(name).URLPath()), "application/xml", bytes.NewReader(config))
+}
+
+// Delete deletes a Jenkins job.
+func (s *JobsService) Delete(ctx context.Context, name string) (*http.Response, error) {
+	return s.client.post(ctx, fmt.Sprintf(JobsDeleteURL, JobPath(name).URLPath()), nil)
+}
+
+// Rename renames a Jenkins job. The new name is the short name of the job, not a full name.
+func (s *JobsService) Rename(ctx context.Context, name, newName string) (*http.Response, error) {
+	return s.client.postForm(ctx, fmt.Sprintf(JobsRenameURL, JobPath(name).URLPath()), url.Values{"newName": {newName}})
+}
+
+// Copy creates a new Jenkins job as a copy of an existing one.
+// Both jobs may live in different folders.
+func (s *JobsService) Copy(ctx context.Context, from, to string) (*http.Response, error) {
+	toPath := JobPath(to)
+
+	return s.client.postForm(ctx, fmt.Sprintf(JobsCreateURL, toPath.Parent().URLPath()), url.Values{
+		"name": {toPath.Name()},
+		"mode": {"copy"},
+		"from": {"/" + strings.Join(JobPath(from).Segments(), "/")},
+	})
+}
@@ -0,0 +1,331 @@
+package jenkins
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+func (s *Suite) TestNewFreestyleProjectMarshal() {
+	b, err := xml.Marshal(NewFreestyleProject("test", "make"))
+	s.NoError(err)
+	s.Contains(string(b), "<project><description>test</description>")
+	s.Contains(string(b), "<builders><hudson.tasks.Shell><command>make</command></hudson.tasks.Shell></builders>")
+}
+
+func (s *Suite) TestFreestyleProjectKeepsUnknownElements() {
+	inputXML := `<project>
+  <description>test</description>
+  <properties>
+    <hudson.model.ParametersDefinitionProperty>
+      <parameterDefinitions/>
+    </hudson.model.ParametersDefinitionProperty>
+  </properties>
+  <scm class="hudson.plugins.git.GitSCM" plugin="git@5.2.2">
+    <configVersion>2</configVersion>
+  </scm>
+  <canRoam>true</canRoam>
+  <triggers>
+    <hudson.triggers.TimerTrigger>
+      <spec>H 2 * * *</spec>
+    </hudson.triggers.TimerTrigger>
+  </triggers>
+  <builders>
+    <hudson.tasks.Shell>
+      <command>make</command>
+      <configuredLocalRules/>
+    </hudson.tasks.Shell>
+    <hudson.tasks.Maven>
+      <targets>package</targets>
+    </hudson.tasks.Maven>
+    <hudson.tasks.Shell>
+      <command>make deploy</command>
+    </hudson.tasks.Shell>
+  </builders>
+  <publishers>
+    <hudson.tasks.ArtifactArchiver>
+      <artifacts>target/*.jar</artifacts>
+    </hudson.tasks.ArtifactArchiver>
+  </publishers>
+</project>`
+
+	var project FreestyleProject
+	s.NoError(xml.Unmarshal([]byte(inputXML), &project))
+	s.Equal([]ShellBuilder{
+		{Command: "make", Unknown: []RawElement{{XMLName: xml.Name{Local: "configuredLocalRules"}}}},
+		{Command: "make deploy"},
+	}, project.Builders.Shell)
+
+	project.Builders.Shell[1].Command = "make install"
+	b, err := xml.Marshal(&project)
+	s.NoError(err)
+	s.Contains(string(b), `<scm class="hudson.plugins.git.GitSCM" plugin="git@5.2.2">`)
+	s.Contains(string(b), `<hudson.model.ParametersDefinitionProperty>`)
+	s.Contains(string(b), `<spec>H 2 * * *</spec>`)
+	s.Contains(string(b), `<artifacts>target/*.jar</artifacts>`)
+
+	// The build steps keep their order.
+	s.Contains(string(b), `<builders><hudson.tasks.Shell><command>make</command><configuredLocalRules></configuredLocalRules></hudson.tasks.Shell>`+
+		`<hudson.tasks.Maven>`)
+	s.Contains(string(b), `</hudson.tasks.Maven><hudson.tasks.Shell><command>make install</command></hudson.tasks.Shell></builders>`)
+}
+
+func (s *Suite) TestJobsServiceCreate() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(JobsCreateURL, ""), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("test job", r.URL.Query().Get("name"))
+		s.Equal("application/xml", r.Header.Get("Content-Type"))
+		s.Equal("crumb", r.Header.Get("crumb"))
+
+		body, err := io.ReadAll(r.Body)
+		s.NoError(err)
+		s.Contains(string(body), "<command>make</command>")
+	})
+
+	_, err = client.Jobs.Create(context.Background(), "test job", NewFreestyleProject("", "make"))
+	s.NoError(err)
+}
+
+func (s *Suite) TestJobsServiceCreateMarshalError() {
+	client, err := NewClient()
+	s.NoError(err)
+
+	_, err = client.Jobs.Create(context.Background(), "test", &brokenXML{})
+	s.Error(err)
+}
+
+func (s *Suite) TestJobsServiceCreateError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	_, err = client.Jobs.CreateXML(context.Background(), "test", []byte("<project/>"))
+	s.Error(err)
+}
+
+func (s *Suite) TestJobsServiceGet() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(JobsGetURL, "/job/test"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(
+			`{"_class":"hudson.model.FreeStyleProject","name":"test","buildable":true,"nextBuildNumber":3}`,
+		))
+		s.NoError(err)
+	})
+
+	job, _, err := client.Jobs.Get(context.Background(), "test")
+	s.NoError(err)
+	s.Equal("test", job.Name)
+	s.True(job.Buildable)
+	s.Equal(3, job.NextBuildNumber)
+}
+
+func (s *Suite) TestJobsServiceGetError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	_, resp, err := client.Jobs.Get(context.Background(), "test")
+	s.Error(err)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (s *Suite) TestJobsServiceGetConfig() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(JobsConfigURL, "/job/test"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(`<?xml version='1.1' encoding='UTF-8'?>
+<project>
+  <description>test</description>
+  <canRoam>true</canRoam>
+  <disabled>true</disabled>
+  <builders>
+    <hudson.tasks.Shell>
+      <command>make</command>
+    </hudson.tasks.Shell>
+  </builders>
+</project>`))
+		s.NoError(err)
+	})
+
+	var project FreestyleProject
+	_, err = client.Jobs.GetConfig(context.Background(), "test", &project)
+	s.NoError(err)
+	s.Equal("test", project.Description)
+	s.True(project.Disabled)
+	s.Equal("make", project.Builders.Shell[0].Command)
+}
+
+func (s *Suite) TestJobsServiceGetConfigError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	var project FreestyleProject
+	_, err = client.Jobs.GetConfig(context.Background(), "test", &project)
+	s.Error(err)
+}
+
+func (s *Suite) TestJobsServiceUpdateConfig() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(JobsConfigURL, "/job/test"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		body, err := io.ReadAll(r.Body)
+		s.NoError(err)
+		s.Contains(string(body), "<description>updated</description>")
+	})
+
+	_, err = client.Jobs.UpdateConfig(context.Background(), "test", &FreestyleProject{Description: "updated"})
+	s.NoError(err)
+
+	_, err = client.Jobs.UpdateConfigXML(context.Background(), "test", []byte("<project><description>updated</description></project>"))
+	s.NoError(err)
+}
+
+func (s *Suite) TestJobsServiceDelete() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(JobsDeleteURL, "/job/test"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+	})
+
+	resp, err := client.Jobs.Delete(context.Background(), "test")
+	s.NoError(err)
+	s.NotNil(resp)
+}
+
+func (s *Suite) TestJobsServiceRename() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(JobsRenameURL, "/job/test"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("renamed", r.FormValue("newName"))
+	})
+
+	_, err = client.Jobs.Rename(context.Background(), "test", "renamed")
+	s.NoError(err)
+}
+
+func (s *Suite) TestJobsServiceCopy() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(JobsCreateURL, ""), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("copy", r.FormValue("mode"))
+		s.Equal("/test", r.FormValue("from"))
+		s.Equal("copied", r.FormValue("name"))
+	})
+
+	_, err = client.Jobs.Copy(context.Background(), "test", "copied")
+	s.NoError(err)
+}
+
+func (s *Suite) TestJobPathURLPath() {
+	s.Equal("/job/team/job/service/job/deploy", JobPath("team/service/deploy").URLPath())
+	s.Equal("/job/team/job/my%20job%3F%23", JobPath("/team//my job?#/").URLPath())
+	s.Equal("", JobPath("").URLPath())
+}
+
+func (s *Suite) TestJobPathSegments() {
+	p := JobPath("team/service/deploy")
+	s.Equal([]string{"team", "service", "deploy"}, p.Segments())
+	s.Equal("deploy", p.Name())
+	s.Equal(JobPath("team/service"), p.Parent())
+	s.Equal(JobPath(""), JobPath("deploy").Parent())
+	s.Equal(JobPath("team/service/deploy/prod"), p.Join("prod"))
+	s.Equal(JobPath("deploy"), JobPath("").Join("deploy"))
+	s.Equal("", JobPath("").Name())
+}
+
+func (s *Suite) TestParseJobURL() {
+	p, err := ParseJobURL("http://127.0.0.1:8080/jenkins/job/team/job/my%20job/")
+	s.NoError(err)
+	s.Equal(JobPath("team/my job"), p)
+
+	p, err = ParseJobURL("/job/deploy")
+	s.NoError(err)
+	s.Equal(JobPath("deploy"), p)
+
+	_, err = ParseJobURL("http://127.0.0.1:8080/job/team/job/deploy/42/")
+	s.Error(err)
+
+	_, err = ParseJobURL("http://127.0.0.1:8080/computer/test/")
+	s.Error(err)
+
+	_, err = ParseJobURL("http://127.0.0.1:8080/job/team/job/%zz/")
+	s.Error(err)
+}
+
+func (s *Suite) TestJobPathRoundTrip() {
+	p := JobPath("team/a b+c%d/deploy")
+	parsed, err := ParseJobURL("http://jenkins" + p.URLPath() + "/")
+	s.NoError(err)
+	s.Equal(p, parsed)
+}
+
+func (s *Suite) TestJobsServiceCreateInFolder() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc("/job/team/job/my service/createItem", func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("/job/team/job/my%20service/createItem", r.URL.EscapedPath())
+		s.Equal("deploy", r.URL.Query().Get("name"))
+	})
+
+	_, err = client.Jobs.CreateXML(context.Background(), "team/my service/deploy", []byte("<project/>"))
+	s.NoError(err)
+}
+
+func (s *Suite) TestJobsServiceCopyAcrossFolders() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc("/job/prod/createItem", func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("/staging/deploy", r.FormValue("from"))
+		s.Equal("deploy", r.FormValue("name"))
+	})
+
+	_, err = client.Jobs.Copy(context.Background(), "staging/deploy", "prod/deploy")
+	s.NoError(err)
+}
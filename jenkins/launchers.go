@@ -2,7 +2,6 @@ package jenkins
 
 import (
 	"encoding/xml"
-	"fmt"
 )
 
 // Launcher is the interface for  all Jenkins node launchers.
@@ -19,7 +18,9 @@ type WorkDirSettings struct {
 type JNLPLauncher struct {
 	StaplerClass string `json:"stapler-class" xml:"class,attr"`
 
-	WebSocket       bool            `json:"websocket" xml:"websocket,omitempty"`
+	// WebSocket connects the agent over WebSocket. Jenkins names the form field and the config.xml
+	// element "webSocket", a "websocket" element is ignored.
+	WebSocket       bool            `json:"webSocket" xml:"webSocket,omitempty"`
 	WorkDirSettings WorkDirSettings `json:"workDirSettings,omitempty" xml:"workDirSettings,omitempty"`
 }
 
@@ -30,6 +31,32 @@ func DefaultJNLPLauncher() *JNLPLauncher {
 	}
 }
 
+// CommandLauncher represents a Jenkins launcher starting the agent by running a command on the controller.
+type CommandLauncher struct {
+	StaplerClass string `json:"stapler-class" xml:"class,attr"`
+
+	Command string `json:"command" xml:"agentCommand"`
+}
+
+// NewCommandLauncher returns a launcher starting the agent with the given command,
+// e.g. "ssh agent-1 java -jar agent.jar".
+func NewCommandLauncher(command string) *CommandLauncher {
+	return &CommandLauncher{
+		StaplerClass: "hudson.slaves.CommandLauncher",
+		Command:      command,
+	}
+}
+
+// RawLauncher represents a Jenkins launcher of a class this package does not know, e.g. of an in-house plugin.
+// Its configuration is kept as raw XML, so it survives getting and updating the node.
+// It cannot be used to create nodes, since Jenkins expects JSON then.
+type RawLauncher struct {
+	StaplerClass string `json:"stapler-class" xml:"class,attr"`
+	Plugin       string `json:"-" xml:"plugin,attr,omitempty"`
+
+	InnerXML string `json:"-" xml:",innerxml"`
+}
+
 // SSHHostKeyVerificationStrategy represents the Jenkins node SSH host key verification strategy.
 type SSHHostKeyVerificationStrategy interface{}
 
@@ -75,7 +102,7 @@ type SSHLauncher struct {
 
 	Host                 string `json:"host" xml:"host"`
 	Port                 int    `json:"port" xml:"port"`
-	CredentialID         string `json:"credentialId" xml:"credentialId"`
+	CredentialID         string `json:"credentialsId" xml:"credentialsId"`
 	LaunchTimeoutSeconds int    `json:"launchTimeoutSeconds" xml:"launchTimeoutSeconds"`
 	MaxNumRetries        int    `json:"maxNumRetries" xml:"maxNumRetries"`
 	RetryWaitTime        int    `json:"retryWaitTime" xml:"retryWaitTime"`
@@ -117,34 +144,12 @@ func (n *SSHLauncher) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error
 		return err
 	}
 
-	// Converts InnerXML to a valid XMl document
-	itemXML := []byte(fmt.Sprintf("<root>%s</root>", v.SSHHostKeyVerificationStrategy.InnerXML))
-
-	switch v.SSHHostKeyVerificationStrategy.Class {
-	case "hudson.plugins.sshslaves.verifiers.NonVerifyingKeyVerificationStrategy":
-		n.SSHHostKeyVerificationStrategy = &NonVerifyingKeyVerificationStrategy{
-			StaplerClass: v.SSHHostKeyVerificationStrategy.Class,
-		}
-	case "hudson.plugins.sshslaves.verifiers.KnownHostsFileKeyVerificationStrategy":
-		n.SSHHostKeyVerificationStrategy = &KnownHostsFileKeyVerificationStrategy{
-			StaplerClass: v.SSHHostKeyVerificationStrategy.Class,
-		}
-	case "hudson.plugins.sshslaves.verifiers.ManuallyProvidedKeyVerificationStrategy":
-		n.SSHHostKeyVerificationStrategy = &ManuallyProvidedKeyVerificationStrategy{
-			StaplerClass: v.SSHHostKeyVerificationStrategy.Class,
-		}
-		err := xml.Unmarshal(itemXML, n.SSHHostKeyVerificationStrategy)
-		if err != nil {
-			return err
-		}
-	case "hudson.plugins.sshslaves.verifiers.ManuallyTrustedKeyVerificationStrategy":
-		n.SSHHostKeyVerificationStrategy = &ManuallyTrustedKeyVerificationStrategy{
-			StaplerClass: v.SSHHostKeyVerificationStrategy.Class,
-		}
-		err := xml.Unmarshal(itemXML, n.SSHHostKeyVerificationStrategy)
-		if err != nil {
-			return err
-		}
+	strategy, ok, err := hostKeyVerificationStrategies.decode(v.SSHHostKeyVerificationStrategy.Class, "", v.SSHHostKeyVerificationStrategy.InnerXML)
+	if err != nil {
+		return err
+	}
+	if ok {
+		n.SSHHostKeyVerificationStrategy = strategy
 	}
 
 	return nil
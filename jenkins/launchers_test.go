@@ -1,6 +1,9 @@
 package jenkins
 
-import "encoding/xml"
+import (
+	"encoding/json"
+	"encoding/xml"
+)
 
 func (s *Suite) TestSSHLauncherMarshalNonVerifyingKeyVerificationStrategy() {
 	inputXML := `<launcher class="hudson.plugins.sshslaves.SSHLauncher" plugin="ssh-slaves@1.33.0">
@@ -82,3 +85,93 @@ func (s *Suite) TestSSHLauncherMarshalManuallyTrustedKeyVerificationStrategy() {
 	s.Equal("hudson.plugins.sshslaves.verifiers.ManuallyTrustedKeyVerificationStrategy", launcher.SSHHostKeyVerificationStrategy.(*ManuallyTrustedKeyVerificationStrategy).StaplerClass)
 	s.True(launcher.SSHHostKeyVerificationStrategy.(*ManuallyTrustedKeyVerificationStrategy).RequireInitialManualTrust)
 }
+
+func (s *Suite) TestNodeUnmarshalCommandLauncher() {
+	inputXML := `<slave>
+  <name>test</name>
+  <launcher class="hudson.slaves.CommandLauncher" plugin="command-launcher@1.6">
+    <agentCommand>ssh agent-1 java -jar agent.jar</agentCommand>
+    <env serialization="custom"><unserializable-parents/></env>
+  </launcher>
+</slave>`
+	var node Node
+	err := xml.Unmarshal([]byte(inputXML), &node)
+	s.NoError(err)
+	s.Equal(NewCommandLauncher("ssh agent-1 java -jar agent.jar"), node.Launcher)
+
+	b, err := xml.Marshal(&node)
+	s.NoError(err)
+	s.Contains(string(b), `<launcher class="hudson.slaves.CommandLauncher"><agentCommand>ssh agent-1 java -jar agent.jar</agentCommand></launcher>`)
+}
+
+func (s *Suite) TestCommandLauncherMarshalJSON() {
+	b, err := json.Marshal(NewCommandLauncher("start-agent.sh"))
+	s.NoError(err)
+	s.JSONEq(`{"stapler-class":"hudson.slaves.CommandLauncher","command":"start-agent.sh"}`, string(b))
+}
+
+func (s *Suite) TestNodeUnmarshalRawLauncher() {
+	inputXML := `<slave>
+  <name>test</name>
+  <launcher class="com.example.CustomLauncher" plugin="custom@1.0">
+    <endpoint>https://agents.example.com</endpoint>
+    <options><retries>3</retries></options>
+  </launcher>
+  <label>custom</label>
+</slave>`
+	var node Node
+	err := xml.Unmarshal([]byte(inputXML), &node)
+	s.NoError(err)
+
+	launcher, ok := node.Launcher.(*RawLauncher)
+	s.True(ok)
+	s.Equal("com.example.CustomLauncher", launcher.StaplerClass)
+	s.Equal("custom@1.0", launcher.Plugin)
+
+	b, err := xml.Marshal(&node)
+	s.NoError(err)
+	s.Contains(string(b), `<launcher class="com.example.CustomLauncher" plugin="custom@1.0">
+    <endpoint>https://agents.example.com</endpoint>
+    <options><retries>3</retries></options>
+  </launcher>`)
+
+	var again Node
+	s.NoError(xml.Unmarshal(b, &again))
+	s.Equal(node.Launcher, again.Launcher)
+}
+
+func (s *Suite) TestJNLPLauncherWebSocket() {
+	launcher := &JNLPLauncher{StaplerClass: "hudson.slaves.JNLPLauncher", WebSocket: true}
+
+	b, err := xml.Marshal(launcher)
+	s.NoError(err)
+	s.Contains(string(b), "<webSocket>true</webSocket>")
+
+	b, err = json.Marshal(launcher)
+	s.NoError(err)
+	s.Contains(string(b), `"webSocket":true`)
+}
+
+func (s *Suite) TestNodeUnmarshalLauncherClass() {
+	var node Node
+	err := xml.Unmarshal([]byte(`<slave><launcher class="hudson.slaves.JNLPLauncher"><webSocket>true</webSocket></launcher></slave>`), &node)
+	s.NoError(err)
+	s.Equal("hudson.slaves.JNLPLauncher", node.Launcher.(*JNLPLauncher).StaplerClass)
+	s.True(node.Launcher.(*JNLPLauncher).WebSocket)
+
+	var bare Node
+	err = xml.Unmarshal([]byte(`<slave><name>test</name></slave>`), &bare)
+	s.NoError(err)
+	s.Nil(bare.Launcher)
+}
+
+func (s *Suite) TestSSHLauncherCredentialsID() {
+	var launcher SSHLauncher
+	err := xml.Unmarshal([]byte(`<launcher class="hudson.plugins.sshslaves.SSHLauncher"><credentialsId>ssh</credentialsId></launcher>`), &launcher)
+	s.NoError(err)
+	s.Equal("ssh", launcher.CredentialID)
+
+	b, err := json.Marshal(NewSSHLauncher("agent", 22, "ssh", 60, 10, 15, true, NewNonVerifyingKeyVerificationStrategy()))
+	s.NoError(err)
+	s.Contains(string(b), `"credentialsId":"ssh"`)
+}
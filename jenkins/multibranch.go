@@ -0,0 +1,582 @@
+// Copyright 2021 The go-jenkins AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jenkins
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// The multibranch URLs below are formatted with JobPath.URLPath.
+const (
+	// MultibranchScanURL is the URL to schedule a scan of a multibranch project or organization folder
+	MultibranchScanURL = "%s/build?delay=0"
+	// MultibranchViewURL is the URL to list the jobs of a branch category of a multibranch project
+	MultibranchViewURL = "%s/view/%s/api/json"
+	// MultibranchComputationURL is the URL to get the last scan of a multibranch project or organization folder
+	MultibranchComputationURL = "%s/computation/api/json"
+	// MultibranchComputationLogURL is the URL to read the log of the last scan in chunks
+	MultibranchComputationLogURL = "%s/computation/logText/progressiveText"
+)
+
+const (
+	// MultiBranchProjectClass is the class name of a multibranch Pipeline project
+	MultiBranchProjectClass = "org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject"
+	// OrganizationFolderClass is the class name of an organization folder
+	OrganizationFolderClass = "jenkins.branch.OrganizationFolder"
+
+	// multibranchBranchesView and multibranchPullRequestsView are the views
+	// Jenkins groups the branch and pull request jobs of a multibranch project in.
+	multibranchBranchesView     = "default"
+	multibranchPullRequestsView = "change-requests"
+)
+
+// WorkflowMultiBranchProject represents the config.xml of a multibranch Pipeline project.
+// Elements it does not model, e.g. the periodic scan triggers and the folder properties,
+// are kept in Unknown, so a decoded project can be updated without losing them.
+type WorkflowMultiBranchProject struct {
+	XMLName xml.Name `xml:"org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject"`
+	Plugin  string   `xml:"plugin,attr,omitempty"`
+
+	Description          string                        `xml:"description"`
+	DisplayName          string                        `xml:"displayName,omitempty"`
+	OrphanedItemStrategy *DefaultOrphanedItemStrategy  `xml:"orphanedItemStrategy,omitempty"`
+	Sources              BranchSources                 `xml:"sources"`
+	Factory              *WorkflowBranchProjectFactory `xml:"factory"`
+
+	Unknown []RawElement `xml:",any"`
+}
+
+// NewWorkflowMultiBranchProject returns a multibranch Pipeline project building the script at scriptPath,
+// e.g. "Jenkinsfile", of every branch discovered by the given sources.
+func NewWorkflowMultiBranchProject(description, scriptPath string, sources ...SCMSource) *WorkflowMultiBranchProject {
+	project := &WorkflowMultiBranchProject{
+		Plugin:      "workflow-multibranch",
+		Description: description,
+		Factory: &WorkflowBranchProjectFactory{
+			StaplerClass: "org.jenkinsci.plugins.workflow.multibranch.WorkflowBranchProjectFactory",
+			ScriptPath:   scriptPath,
+		},
+	}
+
+	for _, source := range sources {
+		project.Sources = append(project.Sources, BranchSource{Source: source})
+	}
+
+	return project
+}
+
+// WorkflowBranchProjectFactory represents the Pipeline script location of the branch jobs of a multibranch project.
+type WorkflowBranchProjectFactory struct {
+	StaplerClass string `xml:"class,attr"`
+
+	ScriptPath string `xml:"scriptPath"`
+}
+
+// DefaultOrphanedItemStrategy represents how long the jobs of removed branches are kept.
+// A value of -1 keeps them forever.
+type DefaultOrphanedItemStrategy struct {
+	StaplerClass string `xml:"class,attr"`
+	Plugin       string `xml:"plugin,attr,omitempty"`
+
+	PruneDeadBranches bool `xml:"pruneDeadBranches"`
+	DaysToKeep        int  `xml:"daysToKeep"`
+	NumToKeep         int  `xml:"numToKeep"`
+}
+
+// NewDefaultOrphanedItemStrategy returns a strategy pruning the jobs of removed branches.
+func NewDefaultOrphanedItemStrategy(daysToKeep, numToKeep int) *DefaultOrphanedItemStrategy {
+	return &DefaultOrphanedItemStrategy{
+		StaplerClass:      "com.cloudbees.hudson.plugins.folder.computed.DefaultOrphanedItemStrategy",
+		Plugin:            "cloudbees-folder",
+		PruneDeadBranches: true,
+		DaysToKeep:        daysToKeep,
+		NumToKeep:         numToKeep,
+	}
+}
+
+// BranchSources represents the branch sources of a multibranch project.
+// Jenkins stores them in a list of its own class, which is added when marshalling.
+type BranchSources []BranchSource
+
+// MarshalXML implements the xml.Marshaler interface.
+func (s BranchSources) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(branchSourceList{
+		Class:   "jenkins.branch.MultiBranchProject$BranchSourceList",
+		Sources: s,
+	}, start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (s *BranchSources) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v branchSourceList
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+
+	*s = v.Sources
+
+	return nil
+}
+
+type branchSourceList struct {
+	Class   string         `xml:"class,attr"`
+	Sources []BranchSource `xml:"data>jenkins.branch.BranchSource"`
+}
+
+// BranchSource represents a branch source of a multibranch project.
+type BranchSource struct {
+	Source   SCMSource               `xml:"source"`
+	Strategy *BranchPropertyStrategy `xml:"strategy,omitempty"`
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+// It decodes the XML child source node into the corresponding SCMSource.
+func (b *BranchSource) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Alias BranchSource // avoids recursive unmarshal
+	v := &struct {
+		Source struct {
+			InnerXML []byte `xml:",innerxml"`   // Stores inner XML of the <source> element
+			Class    string `xml:"class,attr"`  // Stores the class name from the <class> attribute
+			Plugin   string `xml:"plugin,attr"` // Stores the plugin from the <plugin> attribute
+		} `xml:"source"`
+		*Alias
+	}{
+		Alias: (*Alias)(b),
+	}
+
+	if err := d.DecodeElement(v, &start); err != nil {
+		return err
+	}
+
+	// Converts InnerXML to a valid XMl document
+	sourceXML := []byte(fmt.Sprintf("<root>%s</root>", v.Source.InnerXML))
+
+	switch v.Source.Class {
+	case "jenkins.plugins.git.GitSCMSource":
+		b.Source = &GitSCMSource{
+			StaplerClass: v.Source.Class,
+			Plugin:       v.Source.Plugin,
+		}
+	case "org.jenkinsci.plugins.github_branch_source.GitHubSCMSource":
+		b.Source = &GitHubSCMSource{
+			StaplerClass: v.Source.Class,
+			Plugin:       v.Source.Plugin,
+		}
+	case "com.cloudbees.jenkins.plugins.bitbucket.BitbucketSCMSource":
+		b.Source = &BitbucketSCMSource{
+			StaplerClass: v.Source.Class,
+			Plugin:       v.Source.Plugin,
+		}
+	case "":
+		return nil
+	default:
+		b.Source = &RawSCMSource{
+			StaplerClass: v.Source.Class,
+			Plugin:       v.Source.Plugin,
+			InnerXML:     string(v.Source.InnerXML),
+		}
+		return nil
+	}
+
+	return xml.Unmarshal(sourceXML, b.Source)
+}
+
+// BranchPropertyStrategy represents the properties applied to the branch jobs of a branch source.
+// Its configuration is kept as raw XML.
+type BranchPropertyStrategy struct {
+	StaplerClass string `xml:"class,attr"`
+
+	InnerXML string `xml:",innerxml"`
+}
+
+// SCMSource is the interface for all branch sources of a multibranch project.
+type SCMSource interface{}
+
+// RawSCMSource represents a branch source of a class this package does not model.
+// Its configuration is kept as raw XML, so it survives an update.
+type RawSCMSource struct {
+	StaplerClass string `xml:"class,attr"`
+	Plugin       string `xml:"plugin,attr,omitempty"`
+	InnerXML     string `xml:",innerxml"`
+}
+
+// GitSCMSource represents a branch source discovering the branches of a plain Git repository.
+type GitSCMSource struct {
+	StaplerClass string `xml:"class,attr"`
+	Plugin       string `xml:"plugin,attr,omitempty"`
+
+	ID            string    `xml:"id,omitempty"`
+	Remote        string    `xml:"remote"`
+	CredentialsID string    `xml:"credentialsId"`
+	Traits        SCMTraits `xml:"traits"`
+}
+
+// NewGitSCMSource returns a branch source discovering all branches of a Git repository.
+// The credentials ID may be empty for public repositories.
+func NewGitSCMSource(remote, credentialsID string) *GitSCMSource {
+	return &GitSCMSource{
+		StaplerClass:  "jenkins.plugins.git.GitSCMSource",
+		Plugin:        "git",
+		Remote:        remote,
+		CredentialsID: credentialsID,
+		Traits:        SCMTraits{{XMLName: xml.Name{Local: "jenkins.plugins.git.traits.BranchDiscoveryTrait"}}},
+	}
+}
+
+// GitHubSCMSource represents a branch source discovering the branches and pull requests of a GitHub repository.
+type GitHubSCMSource struct {
+	StaplerClass string `xml:"class,attr"`
+	Plugin       string `xml:"plugin,attr,omitempty"`
+
+	ID            string    `xml:"id,omitempty"`
+	APIURI        string    `xml:"apiUri,omitempty"`
+	CredentialsID string    `xml:"credentialsId"`
+	RepoOwner     string    `xml:"repoOwner"`
+	Repository    string    `xml:"repository"`
+	RepositoryURL string    `xml:"repositoryUrl,omitempty"`
+	Traits        SCMTraits `xml:"traits"`
+}
+
+// NewGitHubSCMSource returns a branch source discovering the branches and the pull requests from the origin
+// repository of a github.com repository. Set APIURI for GitHub Enterprise.
+func NewGitHubSCMSource(owner, repository, credentialsID string) *GitHubSCMSource {
+	return &GitHubSCMSource{
+		StaplerClass:  "org.jenkinsci.plugins.github_branch_source.GitHubSCMSource",
+		Plugin:        "github-branch-source",
+		CredentialsID: credentialsID,
+		RepoOwner:     owner,
+		Repository:    repository,
+		Traits:        defaultGitHubTraits(),
+	}
+}
+
+// BitbucketSCMSource represents a branch source discovering the branches and pull requests of a Bitbucket repository.
+type BitbucketSCMSource struct {
+	StaplerClass string `xml:"class,attr"`
+	Plugin       string `xml:"plugin,attr,omitempty"`
+
+	ID            string    `xml:"id,omitempty"`
+	ServerURL     string    `xml:"serverUrl"`
+	CredentialsID string    `xml:"credentialsId"`
+	RepoOwner     string    `xml:"repoOwner"`
+	Repository    string    `xml:"repository"`
+	Traits        SCMTraits `xml:"traits"`
+}
+
+// NewBitbucketSCMSource returns a branch source discovering the branches and the pull requests from the origin
+// repository of a Bitbucket repository, serverURL is "https://bitbucket.org" for Bitbucket Cloud.
+func NewBitbucketSCMSource(serverURL, owner, repository, credentialsID string) *BitbucketSCMSource {
+	return &BitbucketSCMSource{
+		StaplerClass:  "com.cloudbees.jenkins.plugins.bitbucket.BitbucketSCMSource",
+		Plugin:        "cloudbees-bitbucket-branch-source",
+		ServerURL:     serverURL,
+		CredentialsID: credentialsID,
+		RepoOwner:     owner,
+		Repository:    repository,
+		Traits:        defaultBitbucketTraits(),
+	}
+}
+
+// SCMTrait represents a behaviour of a branch source or navigator, e.g. which branches are discovered.
+// Traits are identified by their element name, their configuration is kept as raw XML.
+type SCMTrait struct {
+	XMLName xml.Name
+	Plugin  string `xml:"plugin,attr,omitempty"`
+
+	InnerXML string `xml:",innerxml"`
+}
+
+// SCMTraits represents the traits of a branch source or navigator.
+type SCMTraits []SCMTrait
+
+// MarshalXML implements the xml.Marshaler interface.
+func (t SCMTraits) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(struct {
+		Traits []SCMTrait
+	}{t}, start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (t *SCMTraits) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v struct {
+		Traits []SCMTrait `xml:",any"`
+	}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+
+	*t = v.Traits
+
+	return nil
+}
+
+// defaultGitHubTraits discovers all branches and the pull requests from the origin repository,
+// merged with the target branch.
+func defaultGitHubTraits() SCMTraits {
+	return SCMTraits{
+		{
+			XMLName:  xml.Name{Local: "org.jenkinsci.plugins.github__branch__source.BranchDiscoveryTrait"},
+			InnerXML: "<strategyId>1</strategyId>",
+		},
+		{
+			XMLName:  xml.Name{Local: "org.jenkinsci.plugins.github__branch__source.OriginPullRequestDiscoveryTrait"},
+			InnerXML: "<strategyId>1</strategyId>",
+		},
+	}
+}
+
+// defaultBitbucketTraits discovers all branches and the pull requests from the origin repository,
+// merged with the target branch.
+func defaultBitbucketTraits() SCMTraits {
+	return SCMTraits{
+		{
+			XMLName:  xml.Name{Local: "com.cloudbees.jenkins.plugins.bitbucket.BranchDiscoveryTrait"},
+			InnerXML: "<strategyId>1</strategyId>",
+		},
+		{
+			XMLName:  xml.Name{Local: "com.cloudbees.jenkins.plugins.bitbucket.OriginPullRequestDiscoveryTrait"},
+			InnerXML: "<strategyId>1</strategyId>",
+		},
+	}
+}
+
+// OrganizationFolder represents the config.xml of an organization folder,
+// which creates a multibranch project for every repository its navigators discover.
+// Elements it does not model are kept in Unknown, like for WorkflowMultiBranchProject.
+type OrganizationFolder struct {
+	XMLName xml.Name `xml:"jenkins.branch.OrganizationFolder"`
+	Plugin  string   `xml:"plugin,attr,omitempty"`
+
+	Description          string                              `xml:"description"`
+	DisplayName          string                              `xml:"displayName,omitempty"`
+	OrphanedItemStrategy *DefaultOrphanedItemStrategy        `xml:"orphanedItemStrategy,omitempty"`
+	Navigators           SCMNavigators                       `xml:"navigators"`
+	ProjectFactories     []WorkflowMultiBranchProjectFactory `xml:"projectFactories>org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProjectFactory"`
+
+	Unknown []RawElement `xml:",any"`
+}
+
+// NewOrganizationFolder returns an organization folder creating a multibranch Pipeline project
+// for every repository with a script at scriptPath, e.g. "Jenkinsfile".
+func NewOrganizationFolder(description, scriptPath string, navigators ...SCMNavigator) *OrganizationFolder {
+	return &OrganizationFolder{
+		Plugin:           "branch-api",
+		Description:      description,
+		Navigators:       navigators,
+		ProjectFactories: []WorkflowMultiBranchProjectFactory{{Plugin: "workflow-multibranch", ScriptPath: scriptPath}},
+	}
+}
+
+// WorkflowMultiBranchProjectFactory represents the Pipeline script location of the projects of an organization folder.
+type WorkflowMultiBranchProjectFactory struct {
+	Plugin string `xml:"plugin,attr,omitempty"`
+
+	ScriptPath string `xml:"scriptPath"`
+}
+
+// SCMNavigator is the interface for all navigators of an organization folder.
+// Navigators are identified by their element name.
+type SCMNavigator interface{}
+
+// SCMNavigators represents the navigators of an organization folder.
+type SCMNavigators []SCMNavigator
+
+// MarshalXML implements the xml.Marshaler interface.
+func (n SCMNavigators) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(struct {
+		Navigators []SCMNavigator
+	}{n}, start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+// It decodes the XML child nodes into the corresponding SCMNavigator, unknown navigators into a *RawElement.
+func (n *SCMNavigators) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*n = nil
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			var navigator SCMNavigator
+			switch t.Name.Local {
+			case "org.jenkinsci.plugins.github__branch__source.GitHubSCMNavigator":
+				navigator = &GitHubSCMNavigator{}
+			case "com.cloudbees.jenkins.plugins.bitbucket.BitbucketSCMNavigator":
+				navigator = &BitbucketSCMNavigator{}
+			default:
+				navigator = &RawElement{}
+			}
+
+			if err := d.DecodeElement(navigator, &t); err != nil {
+				return err
+			}
+			*n = append(*n, navigator)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// GitHubSCMNavigator represents a navigator discovering the repositories of a GitHub organization or user.
+type GitHubSCMNavigator struct {
+	XMLName xml.Name `xml:"org.jenkinsci.plugins.github__branch__source.GitHubSCMNavigator"`
+	Plugin  string   `xml:"plugin,attr,omitempty"`
+
+	RepoOwner     string    `xml:"repoOwner"`
+	APIURI        string    `xml:"apiUri,omitempty"`
+	CredentialsID string    `xml:"credentialsId"`
+	Traits        SCMTraits `xml:"traits"`
+}
+
+// NewGitHubSCMNavigator returns a navigator discovering the repositories of a github.com organization or user.
+// Set APIURI for GitHub Enterprise.
+func NewGitHubSCMNavigator(owner, credentialsID string) *GitHubSCMNavigator {
+	return &GitHubSCMNavigator{
+		Plugin:        "github-branch-source",
+		RepoOwner:     owner,
+		CredentialsID: credentialsID,
+		Traits:        defaultGitHubTraits(),
+	}
+}
+
+// BitbucketSCMNavigator represents a navigator discovering the repositories of a Bitbucket team or project.
+type BitbucketSCMNavigator struct {
+	XMLName xml.Name `xml:"com.cloudbees.jenkins.plugins.bitbucket.BitbucketSCMNavigator"`
+	Plugin  string   `xml:"plugin,attr,omitempty"`
+
+	ServerURL     string    `xml:"serverUrl"`
+	CredentialsID string    `xml:"credentialsId"`
+	RepoOwner     string    `xml:"repoOwner"`
+	Traits        SCMTraits `xml:"traits"`
+}
+
+// NewBitbucketSCMNavigator returns a navigator discovering the repositories of a Bitbucket team or project,
+// serverURL is "https://bitbucket.org" for Bitbucket Cloud.
+func NewBitbucketSCMNavigator(serverURL, owner, credentialsID string) *BitbucketSCMNavigator {
+	return &BitbucketSCMNavigator{
+		Plugin:        "cloudbees-bitbucket-branch-source",
+		ServerURL:     serverURL,
+		CredentialsID: credentialsID,
+		RepoOwner:     owner,
+		Traits:        defaultBitbucketTraits(),
+	}
+}
+
+// FolderComputation represents a scan of a multibranch project or organization folder.
+// Jenkins calls it branch indexing for multibranch projects and organization scan for organization folders.
+type FolderComputation struct {
+	Class     string      `json:"_class"`
+	Result    BuildResult `json:"result"`
+	Timestamp int64       `json:"timestamp"`
+	Duration  int64       `json:"duration"`
+}
+
+// StartTime returns the time the scan started.
+func (c *FolderComputation) StartTime() time.Time {
+	return time.UnixMilli(c.Timestamp)
+}
+
+// MultibranchService handles communication with the multibranch project and organization folder
+// related methods of the Jenkins API. Use JobsService to create and configure them,
+// e.g. with NewWorkflowMultiBranchProject or NewOrganizationFolder.
+type MultibranchService service
+
+// Branches returns the branch jobs of a multibranch project.
+func (s *MultibranchService) Branches(ctx context.Context, name string) ([]Job, *http.Response, error) {
+	return s.listView(ctx, name, multibranchBranchesView)
+}
+
+// PullRequests returns the pull request jobs of a multibranch project.
+func (s *MultibranchService) PullRequests(ctx context.Context, name string) ([]Job, *http.Response, error) {
+	return s.listView(ctx, name, multibranchPullRequestsView)
+}
+
+func (s *MultibranchService) listView(ctx context.Context, name, view string) ([]Job, *http.Response, error) {
+	var list Job
+	path := fmt.Sprintf(MultibranchViewURL, JobPath(name).URLPath(), url.PathEscape(view)) +
+		"?" + url.Values{"tree": {folderListTree}}.Encode()
+
+	resp, err := s.client.getJSON(ctx, path, &list)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return list.Jobs, resp, nil
+}
+
+// Scan schedules a scan of a multibranch project or organization folder, like "Scan Multibranch Pipeline Now".
+func (s *MultibranchService) Scan(ctx context.Context, name string) (*http.Response, error) {
+	return s.client.post(ctx, fmt.Sprintf(MultibranchScanURL, JobPath(name).URLPath()), nil)
+}
+
+// GetComputation returns the last scan of a multibranch project or organization folder.
+func (s *MultibranchService) GetComputation(ctx context.Context, name string) (*FolderComputation, *http.Response, error) {
+	var computation FolderComputation
+	resp, err := s.client.getJSON(ctx, fmt.Sprintf(MultibranchComputationURL, JobPath(name).URLPath()), &computation)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &computation, resp, nil
+}
+
+// ScanAndWait schedules a scan and waits until it has finished.
+// If log is not nil, the log of the scan is written to it. The result of the scan is reported
+// in FolderComputation.Result, a failed scan is not an error.
+func (s *MultibranchService) ScanAndWait(ctx context.Context, name string, log io.Writer) (*FolderComputation, *http.Response, error) {
+	previous, resp, err := s.GetComputation(ctx, name)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	resp, err = s.Scan(ctx, name)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var computation *FolderComputation
+	err = s.client.poll(ctx, func() (bool, error) {
+		var err error
+		computation, resp, err = s.GetComputation(ctx, name)
+		if err != nil {
+			return false, err
+		}
+
+		// A scan running when the new one was scheduled has the previous timestamp.
+		return computation.Timestamp != previous.Timestamp && computation.Result != "", nil
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	if log != nil {
+		if _, err := io.Copy(log, s.StreamScanLog(ctx, name)); err != nil {
+			return nil, resp, err
+		}
+	}
+
+	return computation, resp, nil
+}
+
+// StreamScanLog returns a reader of the log of the last scan, see BuildsService.StreamLog.
+func (s *MultibranchService) StreamScanLog(ctx context.Context, name string) *LogStream {
+	return &LogStream{
+		ctx:    ctx,
+		client: s.client,
+		path:   fmt.Sprintf(MultibranchComputationLogURL, JobPath(name).URLPath()),
+		more:   true,
+	}
+}
@@ -0,0 +1,343 @@
+package jenkins
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const multibranchConfigXML = `<?xml version='1.1' encoding='UTF-8'?>
+<org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject plugin="workflow-multibranch@2.26">
+  <actions/>
+  <description>services</description>
+  <properties>
+    <org.jenkinsci.plugins.docker.workflow.declarative.FolderConfig plugin="docker-workflow@1.26">
+      <dockerLabel></dockerLabel>
+      <registry plugin="docker-commons@1.17"/>
+    </org.jenkinsci.plugins.docker.workflow.declarative.FolderConfig>
+  </properties>
+  <folderViews class="jenkins.branch.MultiBranchProjectViewHolder" plugin="branch-api@2.6.2">
+    <owner class="org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject" reference="../.."/>
+  </folderViews>
+  <healthMetrics>
+    <com.cloudbees.hudson.plugins.folder.health.WorstChildHealthMetric plugin="cloudbees-folder@6.15">
+      <nonRecursive>false</nonRecursive>
+    </com.cloudbees.hudson.plugins.folder.health.WorstChildHealthMetric>
+  </healthMetrics>
+  <icon class="jenkins.branch.MetadataActionFolderIcon" plugin="branch-api@2.6.2">
+    <owner class="org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject" reference="../.."/>
+  </icon>
+  <orphanedItemStrategy class="com.cloudbees.hudson.plugins.folder.computed.DefaultOrphanedItemStrategy" plugin="cloudbees-folder@6.15">
+    <pruneDeadBranches>true</pruneDeadBranches>
+    <daysToKeep>7</daysToKeep>
+    <numToKeep>-1</numToKeep>
+  </orphanedItemStrategy>
+  <sources class="jenkins.branch.MultiBranchProject$BranchSourceList" plugin="branch-api@2.6.2">
+    <data>
+      <jenkins.branch.BranchSource>
+        <source class="org.jenkinsci.plugins.github_branch_source.GitHubSCMSource" plugin="github-branch-source@2.9.9">
+          <id>b8f4c1</id>
+          <credentialsId>github</credentialsId>
+          <repoOwner>yarlson</repoOwner>
+          <repository>go-jenkins</repository>
+          <traits>
+            <org.jenkinsci.plugins.github__branch__source.BranchDiscoveryTrait>
+              <strategyId>1</strategyId>
+            </org.jenkinsci.plugins.github__branch__source.BranchDiscoveryTrait>
+          </traits>
+        </source>
+        <strategy class="jenkins.branch.DefaultBranchPropertyStrategy">
+          <properties class="empty-list"/>
+        </strategy>
+      </jenkins.branch.BranchSource>
+      <jenkins.branch.BranchSource>
+        <source class="com.cloudbees.jenkins.plugins.bitbucket.BitbucketSCMSource" plugin="cloudbees-bitbucket-branch-source@2.9.7">
+          <serverUrl>https://bitbucket.org</serverUrl>
+          <credentialsId>bitbucket</credentialsId>
+          <repoOwner>team</repoOwner>
+          <repository>service</repository>
+          <traits/>
+        </source>
+      </jenkins.branch.BranchSource>
+      <jenkins.branch.BranchSource>
+        <source class="jenkins.plugins.git.GitSCMSource" plugin="git@4.7.1">
+          <remote>https://example.com/repo.git</remote>
+          <credentialsId></credentialsId>
+          <traits>
+            <jenkins.plugins.git.traits.BranchDiscoveryTrait/>
+          </traits>
+        </source>
+      </jenkins.branch.BranchSource>
+      <jenkins.branch.BranchSource>
+        <source class="jenkins.scm.impl.SingleSCMSource" plugin="scm-api@2.6.4">
+          <id>single</id>
+          <name>single</name>
+        </source>
+      </jenkins.branch.BranchSource>
+    </data>
+    <owner class="org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject" reference="../.."/>
+  </sources>
+  <factory class="org.jenkinsci.plugins.workflow.multibranch.WorkflowBranchProjectFactory">
+    <owner class="org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject" reference="../.."/>
+    <scriptPath>ci/Jenkinsfile</scriptPath>
+  </factory>
+  <triggers>
+    <com.cloudbees.hudson.plugins.folder.computed.PeriodicFolderTrigger plugin="cloudbees-folder@6.15">
+      <spec>H H/4 * * *</spec>
+      <interval>86400000</interval>
+    </com.cloudbees.hudson.plugins.folder.computed.PeriodicFolderTrigger>
+  </triggers>
+</org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject>`
+
+func (s *Suite) TestNewWorkflowMultiBranchProjectMarshal() {
+	project := NewWorkflowMultiBranchProject("services", "Jenkinsfile",
+		NewGitHubSCMSource("yarlson", "go-jenkins", "github"),
+		NewGitSCMSource("https://example.com/repo.git", ""),
+	)
+	project.OrphanedItemStrategy = NewDefaultOrphanedItemStrategy(7, -1)
+
+	b, err := xml.Marshal(project)
+	s.NoError(err)
+	s.Contains(string(b), `<org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject plugin="workflow-multibranch">`)
+	s.Contains(string(b), `<sources class="jenkins.branch.MultiBranchProject$BranchSourceList"><data><jenkins.branch.BranchSource>`+
+		`<source class="org.jenkinsci.plugins.github_branch_source.GitHubSCMSource" plugin="github-branch-source">`)
+	s.Contains(string(b), `<repoOwner>yarlson</repoOwner><repository>go-jenkins</repository><traits>`+
+		`<org.jenkinsci.plugins.github__branch__source.BranchDiscoveryTrait><strategyId>1</strategyId></org.jenkinsci.plugins.github__branch__source.BranchDiscoveryTrait>`)
+	s.Contains(string(b), `<source class="jenkins.plugins.git.GitSCMSource" plugin="git"><remote>https://example.com/repo.git</remote>`+
+		`<credentialsId></credentialsId><traits><jenkins.plugins.git.traits.BranchDiscoveryTrait></jenkins.plugins.git.traits.BranchDiscoveryTrait></traits>`)
+	s.Contains(string(b), `<factory class="org.jenkinsci.plugins.workflow.multibranch.WorkflowBranchProjectFactory"><scriptPath>Jenkinsfile</scriptPath></factory>`)
+	s.Contains(string(b), `<daysToKeep>7</daysToKeep><numToKeep>-1</numToKeep>`)
+}
+
+func (s *Suite) TestWorkflowMultiBranchProjectUnmarshal() {
+	var project WorkflowMultiBranchProject
+	s.NoError(xml.Unmarshal(xml10([]byte(multibranchConfigXML)), &project))
+
+	s.Equal("services", project.Description)
+	s.Equal("ci/Jenkinsfile", project.Factory.ScriptPath)
+	s.Equal(7, project.OrphanedItemStrategy.DaysToKeep)
+	s.Len(project.Sources, 4)
+
+	github, ok := project.Sources[0].Source.(*GitHubSCMSource)
+	s.True(ok)
+	s.Equal("b8f4c1", github.ID)
+	s.Equal("github-branch-source@2.9.9", github.Plugin)
+	s.Equal("yarlson", github.RepoOwner)
+	s.Equal("go-jenkins", github.Repository)
+	s.Len(github.Traits, 1)
+	s.Equal("org.jenkinsci.plugins.github__branch__source.BranchDiscoveryTrait", github.Traits[0].XMLName.Local)
+	s.Contains(github.Traits[0].InnerXML, "<strategyId>1</strategyId>")
+	s.Equal("jenkins.branch.DefaultBranchPropertyStrategy", project.Sources[0].Strategy.StaplerClass)
+
+	bitbucket, ok := project.Sources[1].Source.(*BitbucketSCMSource)
+	s.True(ok)
+	s.Equal("https://bitbucket.org", bitbucket.ServerURL)
+	s.Equal("service", bitbucket.Repository)
+	s.Empty(bitbucket.Traits)
+
+	git, ok := project.Sources[2].Source.(*GitSCMSource)
+	s.True(ok)
+	s.Equal("https://example.com/repo.git", git.Remote)
+	s.Len(git.Traits, 1)
+
+	raw, ok := project.Sources[3].Source.(*RawSCMSource)
+	s.True(ok)
+	s.Equal("jenkins.scm.impl.SingleSCMSource", raw.StaplerClass)
+	s.Equal("scm-api@2.6.4", raw.Plugin)
+	s.Contains(raw.InnerXML, "<name>single</name>")
+
+	// The decoded project marshals back into an equivalent configuration.
+	b, err := xml.Marshal(&project)
+	s.NoError(err)
+	s.Contains(string(b), `<source class="jenkins.scm.impl.SingleSCMSource" plugin="scm-api@2.6.4">`)
+
+	// Elements the project does not model survive as well.
+	for _, element := range []string{
+		`<org.jenkinsci.plugins.docker.workflow.declarative.FolderConfig plugin="docker-workflow@1.26">`,
+		`<folderViews class="jenkins.branch.MultiBranchProjectViewHolder" plugin="branch-api@2.6.2">`,
+		`<com.cloudbees.hudson.plugins.folder.health.WorstChildHealthMetric plugin="cloudbees-folder@6.15">`,
+		`<icon class="jenkins.branch.MetadataActionFolderIcon" plugin="branch-api@2.6.2">`,
+		`<com.cloudbees.hudson.plugins.folder.computed.PeriodicFolderTrigger plugin="cloudbees-folder@6.15">`,
+		`<spec>H H/4 * * *</spec>`,
+	} {
+		s.Contains(string(b), element)
+	}
+
+	var again WorkflowMultiBranchProject
+	s.NoError(xml.Unmarshal(b, &again))
+	s.Equal(project.Sources, again.Sources)
+	s.Equal(project.Unknown, again.Unknown)
+}
+
+func (s *Suite) TestOrganizationFolderMarshal() {
+	folder := NewOrganizationFolder("org", "Jenkinsfile",
+		NewGitHubSCMNavigator("yarlson", "github"),
+		NewBitbucketSCMNavigator("https://bitbucket.org", "team", "bitbucket"),
+	)
+
+	b, err := xml.Marshal(folder)
+	s.NoError(err)
+	s.Contains(string(b), `<jenkins.branch.OrganizationFolder plugin="branch-api"><description>org</description><navigators>`+
+		`<org.jenkinsci.plugins.github__branch__source.GitHubSCMNavigator plugin="github-branch-source"><repoOwner>yarlson</repoOwner>`)
+	s.Contains(string(b), `<com.cloudbees.jenkins.plugins.bitbucket.BitbucketSCMNavigator plugin="cloudbees-bitbucket-branch-source">`+
+		`<serverUrl>https://bitbucket.org</serverUrl><credentialsId>bitbucket</credentialsId><repoOwner>team</repoOwner>`)
+	s.Contains(string(b), `<projectFactories><org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProjectFactory plugin="workflow-multibranch">`+
+		`<scriptPath>Jenkinsfile</scriptPath>`)
+
+	var decoded OrganizationFolder
+	s.NoError(xml.Unmarshal(b, &decoded))
+	s.Len(decoded.Navigators, 2)
+	s.Equal("yarlson", decoded.Navigators[0].(*GitHubSCMNavigator).RepoOwner)
+	s.Equal(folder.Navigators[1].(*BitbucketSCMNavigator).Traits, decoded.Navigators[1].(*BitbucketSCMNavigator).Traits)
+	s.Equal(folder.ProjectFactories, decoded.ProjectFactories)
+}
+
+func (s *Suite) TestOrganizationFolderUnmarshalUnknownNavigator() {
+	var folder OrganizationFolder
+	s.NoError(xml.Unmarshal([]byte(`<jenkins.branch.OrganizationFolder>
+  <navigators>
+    <jenkins.scm.impl.SingleSCMNavigator><name>single</name></jenkins.scm.impl.SingleSCMNavigator>
+    <org.jenkinsci.plugins.github__branch__source.GitHubSCMNavigator plugin="github-branch-source@2.9.9">
+      <repoOwner>yarlson</repoOwner>
+      <credentialsId>github</credentialsId>
+      <traits/>
+    </org.jenkinsci.plugins.github__branch__source.GitHubSCMNavigator>
+  </navigators>
+  <triggers><com.cloudbees.hudson.plugins.folder.computed.PeriodicFolderTrigger><spec>H H * * *</spec></com.cloudbees.hudson.plugins.folder.computed.PeriodicFolderTrigger></triggers>
+</jenkins.branch.OrganizationFolder>`), &folder))
+
+	s.Len(folder.Navigators, 2)
+	raw, ok := folder.Navigators[0].(*RawElement)
+	s.True(ok)
+	s.Equal("jenkins.scm.impl.SingleSCMNavigator", raw.XMLName.Local)
+	s.Equal("<name>single</name>", raw.InnerXML)
+
+	navigator, ok := folder.Navigators[1].(*GitHubSCMNavigator)
+	s.True(ok)
+	s.Equal("yarlson", navigator.RepoOwner)
+	s.Equal("github-branch-source@2.9.9", navigator.Plugin)
+
+	// The unknown navigator survives an update.
+	b, err := xml.Marshal(&folder)
+	s.NoError(err)
+	s.Contains(string(b), `<navigators><jenkins.scm.impl.SingleSCMNavigator><name>single</name></jenkins.scm.impl.SingleSCMNavigator>`)
+	s.Contains(string(b), `<triggers><com.cloudbees.hudson.plugins.folder.computed.PeriodicFolderTrigger><spec>H H * * *</spec>`)
+}
+
+func (s *Suite) TestMultibranchServiceBranches() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(MultibranchViewURL, "/job/team/job/service", "default"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		s.Equal(folderListTree, r.URL.Query().Get("tree"))
+		_, err := w.Write([]byte(`{"jobs":[{"_class":"org.jenkinsci.plugins.workflow.job.WorkflowJob","name":"main","fullName":"team/service/main"}]}`))
+		s.NoError(err)
+	})
+	s.mux.HandleFunc(fmt.Sprintf(MultibranchViewURL, "/job/team/job/service", "change-requests"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(`{"jobs":[{"_class":"org.jenkinsci.plugins.workflow.job.WorkflowJob","name":"PR-1","fullName":"team/service/PR-1"}]}`))
+		s.NoError(err)
+	})
+
+	branches, _, err := client.Multibranch.Branches(context.Background(), "team/service")
+	s.NoError(err)
+	s.Len(branches, 1)
+	s.Equal("team/service/main", branches[0].FullName)
+
+	pulls, _, err := client.Multibranch.PullRequests(context.Background(), "team/service")
+	s.NoError(err)
+	s.Len(pulls, 1)
+	s.Equal("PR-1", pulls[0].Name)
+
+	_, _, err = client.Multibranch.Branches(context.Background(), "missing")
+	s.Error(err)
+}
+
+func (s *Suite) TestMultibranchServiceScanAndWait() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"), WithPollInterval(time.Millisecond))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	var scheduled, polls int32
+	s.mux.HandleFunc(fmt.Sprintf(MultibranchComputationURL, "/job/service"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		body := `{"_class":"jenkins.branch.MultiBranchProject$BranchIndexing","result":"SUCCESS","timestamp":1000,"duration":5}`
+		if atomic.LoadInt32(&scheduled) == 1 {
+			switch atomic.AddInt32(&polls, 1) {
+			case 1:
+				// The new scan has not started yet.
+			case 2:
+				body = `{"result":null,"timestamp":2000,"duration":0}`
+			default:
+				body = `{"result":"FAILURE","timestamp":2000,"duration":42}`
+			}
+		}
+		_, err := w.Write([]byte(body))
+		s.NoError(err)
+	})
+	s.mux.HandleFunc("/job/service/build", func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("0", r.URL.Query().Get("delay"))
+		atomic.StoreInt32(&scheduled, 1)
+	})
+	s.mux.HandleFunc(fmt.Sprintf(MultibranchComputationLogURL, "/job/service"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		w.Header().Set("X-Text-Size", "20")
+		w.Header().Set("X-More-Data", "false")
+		_, err := w.Write([]byte("Checking branches..."))
+		s.NoError(err)
+	})
+
+	var log bytes.Buffer
+	computation, _, err := client.Multibranch.ScanAndWait(context.Background(), "service", &log)
+	s.NoError(err)
+	s.Equal(BuildResultFailure, computation.Result)
+	s.Equal(int64(42), computation.Duration)
+	s.Equal(time.UnixMilli(2000), computation.StartTime())
+	s.Equal("Checking branches...", log.String())
+}
+
+func (s *Suite) TestMultibranchServiceScanAndWaitError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	_, _, err = client.Multibranch.ScanAndWait(context.Background(), "missing", nil)
+	s.Error(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(MultibranchComputationURL, "/job/service"), func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"result":"SUCCESS","timestamp":1000}`))
+		s.NoError(err)
+	})
+
+	_, _, err = client.Multibranch.ScanAndWait(context.Background(), "service", nil)
+	s.Error(err)
+}
+
+func (s *Suite) TestMultibranchServiceStreamScanLog() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(MultibranchComputationLogURL, "/job/org"), func(w http.ResponseWriter, r *http.Request) {
+		s.Equal("0", r.URL.Query().Get("start"))
+		w.Header().Set("X-Text-Size", "4")
+		_, err := w.Write([]byte("done"))
+		s.NoError(err)
+	})
+
+	b, err := io.ReadAll(client.Multibranch.StreamScanLog(context.Background(), "org"))
+	s.NoError(err)
+	s.Equal("done", string(b))
+}
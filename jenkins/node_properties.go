@@ -0,0 +1,193 @@
+// Copyright 2021 The go-jenkins AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jenkins
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"sort"
+	"strings"
+)
+
+const (
+	// ToolTypeJDK is the type of JDK tool installations
+	ToolTypeJDK = "hudson.model.JDK$DescriptorImpl"
+	// ToolTypeGit is the type of Git tool installations
+	ToolTypeGit = "hudson.plugins.git.GitTool$DescriptorImpl"
+	// ToolTypeMaven is the type of Maven tool installations
+	ToolTypeMaven = "hudson.tasks.Maven$MavenInstallation$DescriptorImpl"
+)
+
+// NodeProperties represents a Jenkins node properties.
+// The JSON keys of the properties are their class names with dots replaced by dashes,
+// as expected by Jenkins when the stapler class bag is set.
+type NodeProperties struct {
+	StaplerClassBag string `json:"stapler-class-bag" xml:"-"`
+
+	EnvironmentVariables *EnvironmentVariablesNodeProperty `json:"hudson-slaves-EnvironmentVariablesNodeProperty,omitempty" xml:"hudson.slaves.EnvironmentVariablesNodeProperty,omitempty"`
+	ToolLocations        *ToolLocationNodeProperty         `json:"hudson-tools-ToolLocationNodeProperty,omitempty" xml:"hudson.tools.ToolLocationNodeProperty,omitempty"`
+	DiskSpaceMonitor     *DiskSpaceMonitorNodeProperty     `json:"hudson-node_monitors-DiskSpaceMonitorNodeProperty,omitempty" xml:"hudson.node__monitors.DiskSpaceMonitorNodeProperty,omitempty"`
+}
+
+// DefaultNodeProperties returns the default node properties.
+func DefaultNodeProperties() *NodeProperties {
+	return &NodeProperties{
+		StaplerClassBag: "true",
+	}
+}
+
+// EnvironmentVariable represents an environment variable of a node.
+type EnvironmentVariable struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// EnvironmentVariablesNodeProperty represents the environment variables set for all builds on a node.
+type EnvironmentVariablesNodeProperty struct {
+	Env []EnvironmentVariable `json:"env"`
+}
+
+// NewEnvironmentVariablesNodeProperty returns a node property setting the given environment variables.
+func NewEnvironmentVariablesNodeProperty(env map[string]string) *EnvironmentVariablesNodeProperty {
+	property := &EnvironmentVariablesNodeProperty{}
+	for key, value := range env {
+		property.Env = append(property.Env, EnvironmentVariable{Key: key, Value: value})
+	}
+
+	property.sort()
+
+	return property
+}
+
+// Map returns the environment variables as a map.
+func (p *EnvironmentVariablesNodeProperty) Map() map[string]string {
+	env := make(map[string]string, len(p.Env))
+	for _, v := range p.Env {
+		env[v.Key] = v.Value
+	}
+
+	return env
+}
+
+// sort sorts the variables case-insensitively like the tree map Jenkins keeps them in.
+func (p *EnvironmentVariablesNodeProperty) sort() {
+	sort.SliceStable(p.Env, func(i, j int) bool {
+		return strings.ToLower(p.Env[i].Key) < strings.ToLower(p.Env[j].Key)
+	})
+}
+
+// envVarsTreeMap represents the custom serialization of the tree map Jenkins keeps environment variables in.
+// The strings are the keys and values of the variables in turns.
+type envVarsTreeMap struct {
+	Serialization         string   `xml:"serialization,attr"`
+	UnserializableParents struct{} `xml:"unserializable-parents"`
+	Comparator            struct {
+		Class string `xml:"class,attr"`
+	} `xml:"tree-map>default>comparator"`
+	Size    int      `xml:"tree-map>int"`
+	Strings []string `xml:"tree-map>string"`
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+// It encodes the variables as the tree map serialized by Jenkins.
+func (p EnvironmentVariablesNodeProperty) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	p.Env = append([]EnvironmentVariable(nil), p.Env...)
+	p.sort()
+
+	envVars := envVarsTreeMap{
+		Serialization: "custom",
+		Size:          len(p.Env),
+	}
+	envVars.Comparator.Class = "java.lang.String$CaseInsensitiveComparator"
+	for _, v := range p.Env {
+		envVars.Strings = append(envVars.Strings, v.Key, v.Value)
+	}
+
+	return e.EncodeElement(struct {
+		EnvVars envVarsTreeMap `xml:"envVars"`
+	}{envVars}, start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+// It decodes the variables from the tree map serialized by Jenkins.
+func (p *EnvironmentVariablesNodeProperty) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v struct {
+		EnvVars envVarsTreeMap `xml:"envVars"`
+	}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+
+	p.Env = nil
+	for i := 0; i+1 < len(v.EnvVars.Strings); i += 2 {
+		p.Env = append(p.Env, EnvironmentVariable{Key: v.EnvVars.Strings[i], Value: v.EnvVars.Strings[i+1]})
+	}
+
+	return nil
+}
+
+// ToolLocation represents the home directory of a tool installation on a node,
+// e.g. the JDK named "jdk17" of type ToolTypeJDK.
+type ToolLocation struct {
+	Type string `xml:"type"`
+	Name string `xml:"name"`
+	Home string `xml:"home"`
+}
+
+// toolLocationJSON represents a tool location as submitted to Jenkins, the key is "<type>@<name>".
+type toolLocationJSON struct {
+	Key  string `json:"key"`
+	Home string `json:"home"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (l ToolLocation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(toolLocationJSON{Key: l.Type + "@" + l.Name, Home: l.Home})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (l *ToolLocation) UnmarshalJSON(data []byte) error {
+	var v toolLocationJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	l.Type, l.Name, _ = strings.Cut(v.Key, "@")
+	l.Home = v.Home
+
+	return nil
+}
+
+// ToolLocationNodeProperty represents the tool installations of a node that differ from the global ones.
+type ToolLocationNodeProperty struct {
+	Locations []ToolLocation `json:"locations" xml:"locations>hudson.tools.ToolLocationNodeProperty_-ToolLocation"`
+}
+
+// NewToolLocationNodeProperty returns a node property with the given tool locations.
+func NewToolLocationNodeProperty(locations ...ToolLocation) *ToolLocationNodeProperty {
+	return &ToolLocationNodeProperty{Locations: locations}
+}
+
+// DiskSpaceMonitorNodeProperty represents the disk space thresholds of a node that differ from the global ones.
+// Thresholds are sizes like "1GiB" or "500MiB". A node is taken offline when its free space falls below
+// the threshold and a warning is logged below the warning threshold.
+type DiskSpaceMonitorNodeProperty struct {
+	FreeDiskSpaceThreshold        string `json:"freeDiskSpaceThreshold" xml:"freeDiskSpaceThreshold"`
+	FreeTempSpaceThreshold        string `json:"freeTempSpaceThreshold" xml:"freeTempSpaceThreshold"`
+	FreeDiskSpaceWarningThreshold string `json:"freeDiskSpaceWarningThreshold" xml:"freeDiskSpaceWarningThreshold"`
+	FreeTempSpaceWarningThreshold string `json:"freeTempSpaceWarningThreshold" xml:"freeTempSpaceWarningThreshold"`
+}
+
+// NewDiskSpaceMonitorNodeProperty returns a node property with the given thresholds
+// for the remote FS root and the temporary directory.
+func NewDiskSpaceMonitorNodeProperty(freeDiskSpace, freeTempSpace, freeDiskSpaceWarning, freeTempSpaceWarning string) *DiskSpaceMonitorNodeProperty {
+	return &DiskSpaceMonitorNodeProperty{
+		FreeDiskSpaceThreshold:        freeDiskSpace,
+		FreeTempSpaceThreshold:        freeTempSpace,
+		FreeDiskSpaceWarningThreshold: freeDiskSpaceWarning,
+		FreeTempSpaceWarningThreshold: freeTempSpaceWarning,
+	}
+}
@@ -0,0 +1,111 @@
+package jenkins
+
+import (
+	"encoding/json"
+	"encoding/xml"
+)
+
+const nodePropertiesXML = `<slave>
+  <name>test</name>
+  <nodeProperties>
+    <hudson.slaves.EnvironmentVariablesNodeProperty>
+      <envVars serialization="custom">
+        <unserializable-parents/>
+        <tree-map>
+          <default>
+            <comparator class="java.lang.String$CaseInsensitiveComparator"/>
+          </default>
+          <int>2</int>
+          <string>GOPATH</string>
+          <string>/go</string>
+          <string>JAVA_OPTS</string>
+          <string>-Xmx1g</string>
+        </tree-map>
+      </envVars>
+    </hudson.slaves.EnvironmentVariablesNodeProperty>
+    <hudson.tools.ToolLocationNodeProperty>
+      <locations>
+        <hudson.tools.ToolLocationNodeProperty_-ToolLocation>
+          <type>hudson.model.JDK$DescriptorImpl</type>
+          <name>jdk17</name>
+          <home>/opt/jdk-17</home>
+        </hudson.tools.ToolLocationNodeProperty_-ToolLocation>
+      </locations>
+    </hudson.tools.ToolLocationNodeProperty>
+    <hudson.node__monitors.DiskSpaceMonitorNodeProperty>
+      <freeDiskSpaceThreshold>2GiB</freeDiskSpaceThreshold>
+      <freeTempSpaceThreshold>1GiB</freeTempSpaceThreshold>
+      <freeDiskSpaceWarningThreshold>4GiB</freeDiskSpaceWarningThreshold>
+      <freeTempSpaceWarningThreshold>2GiB</freeTempSpaceWarningThreshold>
+    </hudson.node__monitors.DiskSpaceMonitorNodeProperty>
+  </nodeProperties>
+</slave>`
+
+func (s *Suite) TestNodePropertiesUnmarshalXML() {
+	var node Node
+	s.NoError(xml.Unmarshal([]byte(nodePropertiesXML), &node))
+
+	s.Equal(map[string]string{"GOPATH": "/go", "JAVA_OPTS": "-Xmx1g"}, node.Properties.EnvironmentVariables.Map())
+	s.Equal([]ToolLocation{{Type: ToolTypeJDK, Name: "jdk17", Home: "/opt/jdk-17"}}, node.Properties.ToolLocations.Locations)
+	s.Equal(NewDiskSpaceMonitorNodeProperty("2GiB", "1GiB", "4GiB", "2GiB"), node.Properties.DiskSpaceMonitor)
+}
+
+func (s *Suite) TestNodePropertiesMarshalXML() {
+	properties := &NodeProperties{
+		StaplerClassBag:      "true",
+		EnvironmentVariables: NewEnvironmentVariablesNodeProperty(map[string]string{"b": "2", "A": "1", "C": "3"}),
+		ToolLocations:        NewToolLocationNodeProperty(ToolLocation{Type: ToolTypeGit, Name: "git", Home: "/usr/bin/git"}),
+	}
+
+	b, err := xml.Marshal(&Node{Name: "test", Properties: properties})
+	s.NoError(err)
+	s.NotContains(string(b), "StaplerClassBag")
+	s.NotContains(string(b), "DiskSpaceMonitorNodeProperty")
+	s.Contains(string(b), `<nodeProperties><hudson.slaves.EnvironmentVariablesNodeProperty><envVars serialization="custom">`+
+		`<unserializable-parents></unserializable-parents><tree-map><default><comparator class="java.lang.String$CaseInsensitiveComparator"></comparator></default>`+
+		`<int>3</int><string>A</string><string>1</string><string>b</string><string>2</string><string>C</string><string>3</string></tree-map></envVars>`)
+	s.Contains(string(b), `<hudson.tools.ToolLocationNodeProperty><locations><hudson.tools.ToolLocationNodeProperty_-ToolLocation>`+
+		`<type>hudson.plugins.git.GitTool$DescriptorImpl</type><name>git</name><home>/usr/bin/git</home>`)
+
+	var node Node
+	s.NoError(xml.Unmarshal(b, &node))
+	s.Equal(properties.EnvironmentVariables, node.Properties.EnvironmentVariables)
+	s.Equal(properties.ToolLocations, node.Properties.ToolLocations)
+}
+
+func (s *Suite) TestNodePropertiesJSON() {
+	properties := &NodeProperties{
+		StaplerClassBag:      "true",
+		EnvironmentVariables: NewEnvironmentVariablesNodeProperty(map[string]string{"GOPATH": "/go"}),
+		ToolLocations:        NewToolLocationNodeProperty(ToolLocation{Type: ToolTypeJDK, Name: "jdk17", Home: "/opt/jdk-17"}),
+		DiskSpaceMonitor:     NewDiskSpaceMonitorNodeProperty("2GiB", "1GiB", "4GiB", "2GiB"),
+	}
+
+	b, err := json.Marshal(properties)
+	s.NoError(err)
+	s.JSONEq(`{
+		"stapler-class-bag": "true",
+		"hudson-slaves-EnvironmentVariablesNodeProperty": {"env": [{"key": "GOPATH", "value": "/go"}]},
+		"hudson-tools-ToolLocationNodeProperty": {"locations": [{"key": "hudson.model.JDK$DescriptorImpl@jdk17", "home": "/opt/jdk-17"}]},
+		"hudson-node_monitors-DiskSpaceMonitorNodeProperty": {
+			"freeDiskSpaceThreshold": "2GiB",
+			"freeTempSpaceThreshold": "1GiB",
+			"freeDiskSpaceWarningThreshold": "4GiB",
+			"freeTempSpaceWarningThreshold": "2GiB"
+		}
+	}`, string(b))
+
+	var decoded NodeProperties
+	s.NoError(json.Unmarshal(b, &decoded))
+	s.Equal(properties, &decoded)
+
+	s.Error(json.Unmarshal([]byte(`{"hudson-tools-ToolLocationNodeProperty": {"locations": [1]}}`), &decoded))
+}
+
+func (s *Suite) TestNodeFillInNodeDefaultsStaplerClassBag() {
+	n := &Node{Properties: &NodeProperties{DiskSpaceMonitor: &DiskSpaceMonitorNodeProperty{}}}
+	n.fillInNodeDefaults()
+
+	s.Equal("true", n.Properties.StaplerClassBag)
+	s.NotNil(n.Properties.DiskSpaceMonitor)
+}
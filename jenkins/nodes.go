@@ -6,13 +6,16 @@
 package jenkins
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 )
 
 // NodeMode represents a Jenkins node mode. Could be either NORMAL or EXCLUSIVE.
@@ -28,26 +31,42 @@ const (
 	NodesCreateURL = "/computer/doCreateItem"
 	// NodesListURL is the URL to list all nodes
 	NodesListURL = "/computer/api/json"
+	// NodesListComputersURL is the URL to list the status of all nodes including their executors
+	NodesListComputersURL = "/computer/api/json?depth=1"
 	// NodesGetURL is the URL to get a node
 	NodesGetURL = "/computer/%s/config.xml"
 	// NodesDeleteURL is the URL to delete a node
 	NodesDeleteURL = "/computer/%s/doDelete"
+	// NodesComputerURL is the URL to get the status of a node
+	NodesComputerURL = "/computer/%s/api/json"
+	// NodesToggleOfflineURL is the URL to mark a node temporarily offline or bring it back online
+	NodesToggleOfflineURL = "/computer/%s/toggleOffline"
+	// NodesDisconnectURL is the URL to disconnect a node
+	NodesDisconnectURL = "/computer/%s/doDisconnect"
+	// NodesLaunchURL is the URL to launch the agent of a node
+	NodesLaunchURL = "/computer/%s/launchSlaveAgent"
 )
 
 // Node represents a Jenkins node.
 type Node struct {
 	XMLName xml.Name `xml:"slave" json:"-"`
 
-	Name               string              `json:"name" xml:"name"`
-	Description        string              `json:"nodeDescription" xml:"description"`
-	RemoteFS           string              `json:"remoteFS" xml:"remoteFS"`
-	NumExecutors       int                 `json:"numExecutors" xml:"numExecutors"`
-	Mode               NodeMode            `json:"mode" xml:"mode"`
-	Type               NodeType            `json:"type" xml:"type"`
-	Labels             Labels              `json:"labelString" xml:"label"`
-	RetentionsStrategy *RetentionsStrategy `json:"retentionsStrategy" xml:"retentionsStrategy"`
-	Properties         *NodeProperties     `json:"nodeProperties" xml:"nodeProperties"`
-	Launcher           Launcher            `json:"launcher" xml:"launcher"`
+	Name               string            `json:"name" xml:"name"`
+	Description        string            `json:"nodeDescription" xml:"description"`
+	RemoteFS           string            `json:"remoteFS" xml:"remoteFS"`
+	NumExecutors       int               `json:"numExecutors" xml:"numExecutors"`
+	Mode               NodeMode          `json:"mode" xml:"mode"`
+	Type               NodeType          `json:"type" xml:"type"`
+	Labels             Labels            `json:"labelString" xml:"label"`
+	RetentionsStrategy RetentionStrategy `json:"retentionStrategy" xml:"retentionStrategy"`
+	Properties         *NodeProperties   `json:"nodeProperties" xml:"nodeProperties"`
+	Launcher           Launcher          `json:"launcher" xml:"launcher"`
+
+	// original is the config.xml the node was decoded from by NodesService.Get,
+	// snapshot is the node encoded right after decoding. Patch writes the changes between
+	// the snapshot and the node to the original, so elements the node does not model survive.
+	original []byte
+	snapshot []byte
 }
 
 // fillInNodeDefaults fills in default values for the node.
@@ -58,6 +77,8 @@ func (n *Node) fillInNodeDefaults() {
 
 	if n.Properties == nil {
 		n.Properties = DefaultNodeProperties()
+	} else if n.Properties.StaplerClassBag == "" {
+		n.Properties.StaplerClassBag = DefaultNodeProperties().StaplerClassBag
 	}
 
 	if n.Type == "" {
@@ -71,18 +92,36 @@ func (n *Node) fillInNodeDefaults() {
 	if n.RetentionsStrategy == nil {
 		n.RetentionsStrategy = DefaultRetentionsStrategy()
 	}
+
+	n.fillInClasses()
+}
+
+// fillInClasses fills in the missing stapler classes of the launcher and the retention strategy of the node
+// with the classes registered for their types.
+func (n *Node) fillInClasses() {
+	launchers.fill(n.Launcher)
+	retentionStrategies.fill(n.RetentionsStrategy)
+
+	if l, ok := n.Launcher.(*SSHLauncher); ok {
+		hostKeyVerificationStrategies.fill(l.SSHHostKeyVerificationStrategy)
+	}
 }
 
 // UnmarshalXML implements the xml.Unmarshaler interface.
 // It decodes the XML attributes into the corresponding struct fields.
-// It also decodes the XML child Launcher nodes into the corresponding struct fields.
+// It also decodes the XML child Launcher and RetentionStrategy nodes into the corresponding struct fields.
 func (n *Node) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	type Alias Node // avoids recursive unmarshal
 	v := &struct {
 		Launcher struct {
-			InnerXML []byte `xml:",innerxml"`  // Stores inner XML of the <launcher> element
-			Class    string `xml:"class,attr"` // Stores the class name from the <class> attribute
+			InnerXML []byte `xml:",innerxml"`   // Stores inner XML of the <launcher> element
+			Class    string `xml:"class,attr"`  // Stores the class name from the <class> attribute
+			Plugin   string `xml:"plugin,attr"` // Stores the plugin from the <plugin> attribute
 		} `xml:"launcher"`
+		RetentionStrategy struct {
+			InnerXML []byte `xml:",innerxml"`  // Stores inner XML of the <retentionStrategy> element
+			Class    string `xml:"class,attr"` // Stores the class name from the <class> attribute
+		} `xml:"retentionStrategy"`
 		*Alias
 	}{
 		Alias: (*Alias)(n),
@@ -92,22 +131,34 @@ func (n *Node) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 		return err
 	}
 
-	// Converts InnerXML to a valid XMl document
-	launcherXML := []byte(fmt.Sprintf("<root>%s</root>", v.Launcher.InnerXML))
-
-	switch v.Launcher.Class {
-	case "hudson.slaves.JNLPLauncher":
-		n.Launcher = &JNLPLauncher{}
-		err := xml.Unmarshal(launcherXML, n.Launcher)
+	if v.Launcher.Class != "" {
+		launcher, ok, err := launchers.decode(v.Launcher.Class, v.Launcher.Plugin, v.Launcher.InnerXML)
 		if err != nil {
 			return err
 		}
-	case "hudson.slaves.SSHLauncher":
-		n.Launcher = &SSHLauncher{}
-		err := xml.Unmarshal(launcherXML, n.Launcher)
+		if !ok {
+			// Keeps launchers of unknown classes verbatim, so they survive an update.
+			launcher = &RawLauncher{
+				StaplerClass: v.Launcher.Class,
+				Plugin:       v.Launcher.Plugin,
+				InnerXML:     string(v.Launcher.InnerXML),
+			}
+		}
+		n.Launcher = launcher
+	}
+
+	if v.RetentionStrategy.Class != "" {
+		strategy, ok, err := retentionStrategies.decode(v.RetentionStrategy.Class, "", v.RetentionStrategy.InnerXML)
 		if err != nil {
 			return err
 		}
+		if !ok {
+			// Strategies of unknown classes keep their class only.
+			strategy = &RetentionsStrategy{
+				StaplerClass: v.RetentionStrategy.Class,
+			}
+		}
+		n.RetentionsStrategy = strategy
 	}
 
 	return nil
@@ -123,26 +174,26 @@ func (l Labels) MarshalJSON() ([]byte, error) {
 	return []byte(labels), nil
 }
 
-// RetentionsStrategy represents a Jenkins node retention strategy.
-type RetentionsStrategy struct {
-	StaplerClass string `json:"stapler-class" xml:"class,attr"`
+// MarshalXML implements the xml.Marshaler interface.
+// Jenkins stores the labels in a single element, separated by spaces.
+func (l Labels) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(strings.Join(l, " "), start)
 }
 
-// DefaultRetentionsStrategy represents the default retention strategy.
-func DefaultRetentionsStrategy() *RetentionsStrategy {
-	return &RetentionsStrategy{StaplerClass: "hudson.slaves.RetentionStrategy$Always"}
-}
-
-// NodeProperties represents a Jenkins node properties.
-type NodeProperties struct {
-	StaplerClassBag string `json:"stapler-class-bag"`
-}
+// UnmarshalXML implements the xml.Unmarshaler interface.
+// It splits the space separated labels of the element.
+func (l *Labels) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var labels string
+	if err := d.DecodeElement(&labels, &start); err != nil {
+		return err
+	}
 
-// DefaultNodeProperties returns the default node properties.
-func DefaultNodeProperties() *NodeProperties {
-	return &NodeProperties{
-		StaplerClassBag: "true",
+	*l = nil
+	for _, label := range strings.Fields(labels) {
+		*l = append(*l, label)
 	}
+
+	return nil
 }
 
 // NodeType represents a Jenkins node type.
@@ -187,20 +238,87 @@ type Computer struct {
 	MonitorData         MonitorData      `json:"monitorData"`
 	NumExecutors        int              `json:"numExecutors"`
 	Offline             bool             `json:"offline"`
-	OfflineCause        interface{}      `json:"offlineCause"`
+	OfflineCause        *OfflineCause    `json:"offlineCause"`
 	OfflineCauseReason  string           `json:"offlineCauseReason"`
-	OneOffExecutors     []interface{}    `json:"oneOffExecutors"`
+	OneOffExecutors     []Executors      `json:"oneOffExecutors"`
 	TemporarilyOffline  bool             `json:"temporarilyOffline"`
 	AbsoluteRemotePath  interface{}      `json:"absoluteRemotePath,omitempty"`
 }
 
+// HasLabel reports whether the label is assigned to the node.
+// The name of a node is one of its labels as well.
+func (c *Computer) HasLabel(label string) bool {
+	for _, assigned := range c.AssignedLabels {
+		if assigned.Name == label {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BusyExecutors returns the executors running a build.
+func (c *Computer) BusyExecutors() []Executors {
+	var busy []Executors
+	for _, executor := range c.Executors {
+		if !executor.Idle {
+			busy = append(busy, executor)
+		}
+	}
+
+	return busy
+}
+
 // AssignedLabels represents a Jenkins assigned labels.
 type AssignedLabels struct {
 	Name string `json:"name"`
 }
 
-// Executors represents a Jenkins executors.
+// Executors represents a Jenkins executor.
+// Only NodesService.ListComputers reports the executors of a node.
 type Executors struct {
+	Number            int       `json:"number"`
+	Idle              bool      `json:"idle"`
+	LikelyStuck       bool      `json:"likelyStuck"`
+	Progress          int       `json:"progress"`
+	CurrentExecutable *BuildRef `json:"currentExecutable"`
+}
+
+// OfflineCause represents why a Jenkins node is offline,
+// e.g. "hudson.slaves.OfflineCause$UserCause" for nodes marked offline by a user.
+type OfflineCause struct {
+	Class       string `json:"_class"`
+	Description string `json:"description"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+// Time returns the time the node went offline.
+func (c *OfflineCause) Time() time.Time {
+	return time.UnixMilli(c.Timestamp)
+}
+
+// ComputerFilter reports whether a node is included by NodesService.ListComputers.
+type ComputerFilter func(c *Computer) bool
+
+// FilterByLabel includes the nodes the label is assigned to.
+func FilterByLabel(label string) ComputerFilter {
+	return func(c *Computer) bool {
+		return c.HasLabel(label)
+	}
+}
+
+// FilterByOffline includes the nodes that are offline, or online if offline is false.
+func FilterByOffline(offline bool) ComputerFilter {
+	return func(c *Computer) bool {
+		return c.Offline == offline
+	}
+}
+
+// FilterByIdle includes the nodes that are not running any builds, or busy if idle is false.
+func FilterByIdle(idle bool) ComputerFilter {
+	return func(c *Computer) bool {
+		return c.Idle == idle
+	}
 }
 
 // LoadStatistics represents a Jenkins load statistics.
@@ -282,7 +400,8 @@ func (s *NodesService) Create(ctx context.Context, node *Node) (*Node, *http.Res
 	return node, resp, nil
 }
 
-// List returns a list of Jenkins nodes.
+// List returns a list of Jenkins nodes with their name and description only.
+// Use ListComputers to get their status.
 func (s *NodesService) List(ctx context.Context) ([]Node, *http.Response, error) {
 	resp, err := s.client.get(ctx, NodesListURL)
 	if err != nil {
@@ -316,37 +435,76 @@ func (s *NodesService) List(ctx context.Context) ([]Node, *http.Response, error)
 	return nodes, nil, nil
 }
 
+// ListComputers returns the status of the Jenkins nodes, including their executors and monitor data.
+// Only nodes accepted by all filters are returned.
+func (s *NodesService) ListComputers(ctx context.Context, filters ...ComputerFilter) ([]Computer, *http.Response, error) {
+	var listResp NodesListResponse
+	resp, err := s.client.getJSON(ctx, NodesListComputersURL, &listResp)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	var computers []Computer
+	for i := range listResp.Computer {
+		if matchComputer(&listResp.Computer[i], filters) {
+			computers = append(computers, listResp.Computer[i])
+		}
+	}
+
+	return computers, resp, nil
+}
+
+func matchComputer(c *Computer, filters []ComputerFilter) bool {
+	for _, filter := range filters {
+		if !filter(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // Get returns a Jenkins node.
+// The node keeps its config.xml, so Patch can update it without losing elements the Node type does not model.
 func (s *NodesService) Get(ctx context.Context, name string) (*Node, *http.Response, error) {
-	resp, err := s.client.get(ctx, fmt.Sprintf(NodesGetURL, name))
+	body, resp, err := s.client.getXML(ctx, fmt.Sprintf(NodesGetURL, name))
 	if err != nil {
 		return nil, resp, err
 	}
 
-	defer func(Body io.ReadCloser) {
-		_ = Body.Close()
-	}(resp.Body)
-
-	body, err := io.ReadAll(resp.Body)
+	node, err := decodeNode(body)
 	if err != nil {
 		return nil, resp, err
 	}
 
-	// Golang cannot unmarshal xml 1.1 documents.
-	// Jenkins XMLs are basically 1.0 documents.
-	body = []byte(strings.Replace(string(body), "xml version=\"1.1\"", "xml version=\"1.0\"", -1))
+	return node, nil, nil
+}
 
+// decodeNode decodes a node from its config.xml and keeps the XML for patching.
+func decodeNode(body []byte) (*Node, error) {
 	var node Node
-	err = xml.Unmarshal(body, &node)
+	err := xml.Unmarshal(body, &node)
 	if err != nil {
-		return nil, resp, err
+		return nil, err
 	}
 
-	return &node, nil, nil
+	snapshot, err := xml.Marshal(&node)
+	if err != nil {
+		return nil, err
+	}
+
+	node.original = body
+	node.snapshot = snapshot
+
+	return &node, nil
 }
 
-// Update updates a Jenkins node.
+// Update updates a Jenkins node. The config.xml of the node is replaced by the encoded node,
+// so node properties, launcher and retention strategy settings and plugin data the Node type
+// does not model are lost. Use Patch to keep them.
 func (s *NodesService) Update(ctx context.Context, node *Node) (*Node, *http.Response, error) {
+	node.fillInClasses()
+
 	resp, err := s.client.post(ctx, fmt.Sprintf(NodesGetURL, node.Name), node)
 	if err != nil {
 		return nil, resp, err
@@ -355,7 +513,114 @@ func (s *NodesService) Update(ctx context.Context, node *Node) (*Node, *http.Res
 	return node, nil, nil
 }
 
+// Patch updates a Jenkins node returned by Get, changing only the elements of its config.xml modified since.
+// Elements the Node type does not model, such as unknown node properties, launcher settings and plugin
+// attributes, are kept as they are. The node is updated to be patched again afterwards.
+func (s *NodesService) Patch(ctx context.Context, node *Node) (*Node, *http.Response, error) {
+	if node.original == nil {
+		return nil, nil, fmt.Errorf("node %s was not returned by Get", node.Name)
+	}
+
+	node.fillInClasses()
+
+	modified, err := xml.Marshal(node)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	patched, err := patchXML(node.original, node.snapshot, modified)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := s.client.postBody(ctx, fmt.Sprintf(NodesGetURL, node.Name), "application/xml", bytes.NewReader(patched))
+	if err != nil {
+		return nil, resp, err
+	}
+
+	node.original = patched
+	node.snapshot = modified
+
+	return node, resp, nil
+}
+
 // Delete deletes a Jenkins node.
 func (s *NodesService) Delete(ctx context.Context, name string) (*http.Response, error) {
 	return s.client.post(ctx, fmt.Sprintf(NodesDeleteURL, name), nil)
 }
+
+// GetComputer returns the status of a Jenkins node, e.g. whether it is online.
+func (s *NodesService) GetComputer(ctx context.Context, name string) (*Computer, *http.Response, error) {
+	var computer Computer
+	resp, err := s.client.getJSON(ctx, fmt.Sprintf(NodesComputerURL, name), &computer)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &computer, resp, nil
+}
+
+// ToggleOffline marks an online node temporarily offline with the given message, or brings a temporarily
+// offline node back online. Running builds are not affected, but the node does not accept new builds.
+func (s *NodesService) ToggleOffline(ctx context.Context, name, message string) (*http.Response, error) {
+	return s.client.postForm(ctx, fmt.Sprintf(NodesToggleOfflineURL, name), url.Values{
+		"offlineMessage": {message},
+	})
+}
+
+// SetOffline marks a node temporarily offline with the given message.
+// The node is left untouched if it already is temporarily offline.
+func (s *NodesService) SetOffline(ctx context.Context, name, message string) (*http.Response, error) {
+	computer, resp, err := s.GetComputer(ctx, name)
+	if err != nil || computer.TemporarilyOffline {
+		return resp, err
+	}
+
+	return s.ToggleOffline(ctx, name, message)
+}
+
+// SetOnline brings a temporarily offline node back online.
+// The node is left untouched if it is not temporarily offline.
+func (s *NodesService) SetOnline(ctx context.Context, name string) (*http.Response, error) {
+	computer, resp, err := s.GetComputer(ctx, name)
+	if err != nil || !computer.TemporarilyOffline {
+		return resp, err
+	}
+
+	return s.ToggleOffline(ctx, name, "")
+}
+
+// Disconnect disconnects the agent of a node with the given message. Running builds are aborted.
+func (s *NodesService) Disconnect(ctx context.Context, name, message string) (*http.Response, error) {
+	return s.client.postForm(ctx, fmt.Sprintf(NodesDisconnectURL, name), url.Values{
+		"offlineMessage": {message},
+	})
+}
+
+// Launch launches the agent of a node, e.g. connects to it over SSH.
+// Agents of nodes with a JNLP launcher connect on their own and cannot be launched.
+func (s *NodesService) Launch(ctx context.Context, name string) (*http.Response, error) {
+	return s.client.post(ctx, fmt.Sprintf(NodesLaunchURL, name), nil)
+}
+
+// WaitUntilOnline polls a node until its agent is connected and returns its status.
+// A node that is connected but temporarily offline is not online.
+func (s *NodesService) WaitUntilOnline(ctx context.Context, name string) (*Computer, *http.Response, error) {
+	var computer *Computer
+	var resp *http.Response
+
+	err := s.client.poll(ctx, func() (bool, error) {
+		var err error
+		computer, resp, err = s.GetComputer(ctx, name)
+		if err != nil {
+			return false, err
+		}
+
+		return !computer.Offline, nil
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return computer, resp, nil
+}
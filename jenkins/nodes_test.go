@@ -2,8 +2,12 @@ package jenkins
 
 import (
 	"context"
+	"encoding/xml"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
 func (s *Suite) TestNodeFillInNodeDefaults() {
@@ -132,6 +136,7 @@ func (s *Suite) TestNodesServiceGet() {
 	s.NoError(err)
 	s.Equal("test", node.Name)
 	s.IsType(&JNLPLauncher{}, node.Launcher)
+	s.Equal(DefaultRetentionsStrategy(), node.RetentionsStrategy)
 }
 
 func (s *Suite) TestNodesServiceGetError() {
@@ -204,6 +209,135 @@ func (s *Suite) TestNodesServiceUpdateError() {
 	s.Error(err)
 }
 
+const nodeConfigWithPluginData = `<?xml version="1.1" encoding="UTF-8"?>
+<slave>
+  <name>test</name>
+  <description>old</description>
+  <remoteFS>/var/lib/jenkins</remoteFS>
+  <numExecutors>1</numExecutors>
+  <mode>EXCLUSIVE</mode>
+  <retentionStrategy class="hudson.slaves.RetentionStrategy$Always"/>
+  <launcher class="hudson.slaves.JNLPLauncher">
+    <workDirSettings>
+      <disabled>false</disabled>
+      <internalDir>remoting</internalDir>
+      <failIfWorkDirIsMissing>false</failIfWorkDirIsMissing>
+    </workDirSettings>
+    <webSocket>false</webSocket>
+  </launcher>
+  <label>test</label>
+  <nodeProperties>
+    <com.example.UnknownNodeProperty plugin="example@1.0">
+      <setting>value</setting>
+    </com.example.UnknownNodeProperty>
+  </nodeProperties>
+  <userId>admin</userId>
+</slave>
+`
+
+func (s *Suite) TestNodesServicePatch() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	var posted []string
+	s.mux.HandleFunc(fmt.Sprintf(NodesGetURL, "test"), func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, err := w.Write([]byte(nodeConfigWithPluginData))
+			s.NoError(err)
+			return
+		}
+
+		s.testMethod(r, "POST")
+		body, err := io.ReadAll(r.Body)
+		s.NoError(err)
+		posted = append(posted, string(body))
+	})
+
+	node, _, err := client.Nodes.Get(context.Background(), "test")
+	s.NoError(err)
+
+	node.Description = "new"
+	node.Launcher.(*JNLPLauncher).WebSocket = true
+
+	_, _, err = client.Nodes.Patch(context.Background(), node)
+	s.NoError(err)
+
+	want := strings.NewReplacer(
+		"<description>old</description>", "<description>new</description>",
+		"<webSocket>false</webSocket>", "<webSocket>true</webSocket>",
+		`version="1.1"`, `version="1.0"`,
+	).Replace(nodeConfigWithPluginData)
+	s.Equal([]string{want}, posted)
+
+	node.NumExecutors = 2
+	_, _, err = client.Nodes.Patch(context.Background(), node)
+	s.NoError(err)
+	s.Len(posted, 2)
+	s.Equal(strings.Replace(want, "<numExecutors>1</numExecutors>", "<numExecutors>2</numExecutors>", 1), posted[1])
+
+	node.Properties.EnvironmentVariables = &EnvironmentVariablesNodeProperty{
+		Env: []EnvironmentVariable{{Key: "JAVA_HOME", Value: "/opt/java"}},
+	}
+	_, _, err = client.Nodes.Patch(context.Background(), node)
+	s.NoError(err)
+	s.Len(posted, 3)
+	s.Contains(posted[2], "<hudson.slaves.EnvironmentVariablesNodeProperty>")
+	s.Contains(posted[2], "<string>JAVA_HOME</string>")
+	s.Contains(posted[2], `<com.example.UnknownNodeProperty plugin="example@1.0">
+      <setting>value</setting>
+    </com.example.UnknownNodeProperty>`)
+
+	node.Properties.EnvironmentVariables = nil
+	_, _, err = client.Nodes.Patch(context.Background(), node)
+	s.NoError(err)
+	s.Len(posted, 4)
+	s.NotContains(posted[3], "EnvironmentVariablesNodeProperty")
+	s.Contains(posted[3], `<com.example.UnknownNodeProperty plugin="example@1.0">`)
+
+	// Jenkins stores the labels space separated in a single element.
+	s.Equal(Labels{"test"}, node.Labels)
+	node.Labels = Labels{"linux", "docker", "java"}
+	_, _, err = client.Nodes.Patch(context.Background(), node)
+	s.NoError(err)
+	s.Len(posted, 5)
+	s.Contains(posted[4], "  <label>linux docker java</label>\n")
+	s.Equal(1, strings.Count(posted[4], "<label>"))
+
+	var patched Node
+	s.NoError(xml.Unmarshal([]byte(posted[4]), &patched))
+	s.Equal(Labels{"linux", "docker", "java"}, patched.Labels)
+}
+
+func (s *Suite) TestNodesServicePatchError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	_, _, err = client.Nodes.Patch(context.Background(), &Node{Name: "test"})
+	s.EqualError(err, "node test was not returned by Get")
+
+	s.addCrumbsHandle()
+	s.mux.HandleFunc(fmt.Sprintf(NodesGetURL, "test"), func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, err := w.Write([]byte(nodeConfigWithPluginData))
+			s.NoError(err)
+			return
+		}
+
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	node, _, err := client.Nodes.Get(context.Background(), "test")
+	s.NoError(err)
+
+	_, resp, err := client.Nodes.Patch(context.Background(), node)
+	s.Error(err)
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+}
+
 func (s *Suite) TestNodesServiceDelete() {
 	s.newMux()
 	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
@@ -219,3 +353,244 @@ func (s *Suite) TestNodesServiceDelete() {
 	s.NoError(err)
 	s.NotNil(resp)
 }
+
+func (s *Suite) TestNodesServiceGetComputer() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(NodesComputerURL, "test"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(`{"displayName":"test","offline":true,"temporarilyOffline":true,"offlineCauseReason":"patching"}`))
+		s.NoError(err)
+	})
+
+	computer, _, err := client.Nodes.GetComputer(context.Background(), "test")
+	s.NoError(err)
+	s.True(computer.TemporarilyOffline)
+	s.Equal("patching", computer.OfflineCauseReason)
+
+	_, _, err = client.Nodes.GetComputer(context.Background(), "missing")
+	s.Error(err)
+}
+
+func (s *Suite) TestNodesServiceToggleOffline() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(NodesToggleOfflineURL, "test"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("crumb", r.Header.Get("crumb"))
+		s.Equal("patching", r.FormValue("offlineMessage"))
+	})
+
+	_, err = client.Nodes.ToggleOffline(context.Background(), "test", "patching")
+	s.NoError(err)
+}
+
+func (s *Suite) TestNodesServiceSetOfflineOnline() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	offline := false
+	toggles := 0
+	s.mux.HandleFunc(fmt.Sprintf(NodesComputerURL, "test"), func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(fmt.Sprintf(`{"offline":%t,"temporarilyOffline":%t}`, offline, offline)))
+		s.NoError(err)
+	})
+	s.mux.HandleFunc(fmt.Sprintf(NodesToggleOfflineURL, "test"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		offline = !offline
+		toggles++
+	})
+
+	_, err = client.Nodes.SetOffline(context.Background(), "test", "patching")
+	s.NoError(err)
+	_, err = client.Nodes.SetOffline(context.Background(), "test", "patching")
+	s.NoError(err)
+	s.True(offline)
+	s.Equal(1, toggles)
+
+	_, err = client.Nodes.SetOnline(context.Background(), "test")
+	s.NoError(err)
+	_, err = client.Nodes.SetOnline(context.Background(), "test")
+	s.NoError(err)
+	s.False(offline)
+	s.Equal(2, toggles)
+
+	_, err = client.Nodes.SetOffline(context.Background(), "missing", "patching")
+	s.Error(err)
+	_, err = client.Nodes.SetOnline(context.Background(), "missing")
+	s.Error(err)
+}
+
+func (s *Suite) TestNodesServiceDisconnectLaunch() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(NodesDisconnectURL, "test"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("reboot", r.FormValue("offlineMessage"))
+	})
+	s.mux.HandleFunc(fmt.Sprintf(NodesLaunchURL, "test"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+	})
+
+	_, err = client.Nodes.Disconnect(context.Background(), "test", "reboot")
+	s.NoError(err)
+
+	_, err = client.Nodes.Launch(context.Background(), "test")
+	s.NoError(err)
+}
+
+func (s *Suite) TestNodesServiceWaitUntilOnline() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"), WithPollInterval(time.Millisecond))
+	s.NoError(err)
+
+	polls := 0
+	s.mux.HandleFunc(fmt.Sprintf(NodesComputerURL, "test"), func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		_, err := w.Write([]byte(fmt.Sprintf(`{"displayName":"test","offline":%t}`, polls < 3)))
+		s.NoError(err)
+	})
+
+	computer, _, err := client.Nodes.WaitUntilOnline(context.Background(), "test")
+	s.NoError(err)
+	s.False(computer.Offline)
+	s.Equal(3, polls)
+
+	_, _, err = client.Nodes.WaitUntilOnline(context.Background(), "missing")
+	s.Error(err)
+}
+
+const computersListJSON = `{
+  "_class": "hudson.model.ComputerSet",
+  "busyExecutors": 1,
+  "totalExecutors": 3,
+  "computer": [
+    {
+      "_class": "hudson.model.Hudson$MasterComputer",
+      "assignedLabels": [{"name": "built-in"}],
+      "displayName": "Built-In Node",
+      "executors": [{"currentExecutable": null, "idle": true, "likelyStuck": false, "number": 0, "progress": -1}],
+      "idle": true,
+      "numExecutors": 1,
+      "offline": false,
+      "offlineCause": null
+    },
+    {
+      "_class": "hudson.slaves.SlaveComputer",
+      "assignedLabels": [{"name": "linux"}, {"name": "agent-1"}],
+      "displayName": "agent-1",
+      "executors": [
+        {
+          "currentExecutable": {"_class": "hudson.model.FreeStyleBuild", "number": 42, "url": "http://jenkins/job/deploy/42/"},
+          "idle": false,
+          "likelyStuck": true,
+          "number": 0,
+          "progress": 73
+        },
+        {"currentExecutable": null, "idle": true, "likelyStuck": false, "number": 1, "progress": -1}
+      ],
+      "idle": false,
+      "numExecutors": 2,
+      "offline": false,
+      "offlineCause": null
+    },
+    {
+      "_class": "hudson.slaves.SlaveComputer",
+      "assignedLabels": [{"name": "linux"}, {"name": "agent-2"}],
+      "displayName": "agent-2",
+      "executors": [],
+      "idle": true,
+      "numExecutors": 1,
+      "offline": true,
+      "offlineCause": {"_class": "hudson.slaves.OfflineCause$UserCause", "description": "Disconnected by admin : patching", "timestamp": 1700000000000},
+      "offlineCauseReason": "patching",
+      "temporarilyOffline": true
+    }
+  ]
+}`
+
+func (s *Suite) TestNodesServiceListComputers() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(NodesListURL, func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		s.Equal("1", r.URL.Query().Get("depth"))
+		_, err := w.Write([]byte(computersListJSON))
+		s.NoError(err)
+	})
+
+	computers, _, err := client.Nodes.ListComputers(context.Background())
+	s.NoError(err)
+	s.Len(computers, 3)
+
+	agent := computers[1]
+	s.Equal("agent-1", agent.DisplayName)
+	s.True(agent.HasLabel("linux"))
+	s.False(agent.HasLabel("windows"))
+	s.Len(agent.BusyExecutors(), 1)
+	s.Equal(Executors{
+		Number:            0,
+		LikelyStuck:       true,
+		Progress:          73,
+		CurrentExecutable: &BuildRef{Class: "hudson.model.FreeStyleBuild", Number: 42, URL: "http://jenkins/job/deploy/42/"},
+	}, agent.Executors[0])
+	s.Nil(agent.OfflineCause)
+
+	offline := computers[2]
+	s.Equal("hudson.slaves.OfflineCause$UserCause", offline.OfflineCause.Class)
+	s.Equal("Disconnected by admin : patching", offline.OfflineCause.Description)
+	s.Equal(time.UnixMilli(1700000000000), offline.OfflineCause.Time())
+}
+
+func (s *Suite) TestNodesServiceListComputersFilters() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(NodesListURL, func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(computersListJSON))
+		s.NoError(err)
+	})
+
+	names := func(filters ...ComputerFilter) []string {
+		computers, _, err := client.Nodes.ListComputers(context.Background(), filters...)
+		s.NoError(err)
+
+		var names []string
+		for _, c := range computers {
+			names = append(names, c.DisplayName)
+		}
+		return names
+	}
+
+	s.Equal([]string{"agent-1", "agent-2"}, names(FilterByLabel("linux")))
+	s.Equal([]string{"agent-2"}, names(FilterByOffline(true)))
+	s.Equal([]string{"agent-1"}, names(FilterByLabel("linux"), FilterByOffline(false)))
+	s.Equal([]string{"Built-In Node", "agent-2"}, names(FilterByIdle(true)))
+	s.Equal([]string{"agent-1"}, names(FilterByIdle(false)))
+	s.Nil(names(FilterByLabel("windows")))
+}
+
+func (s *Suite) TestNodesServiceListComputersError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	_, _, err = client.Nodes.ListComputers(context.Background())
+	s.Error(err)
+}
@@ -0,0 +1,568 @@
+// Copyright 2021 The go-jenkins AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jenkins
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// The Pipeline URLs below are formatted with JobPath.URLPath and the build number.
+const (
+	// PipelinesDescribeURL is the URL to describe the stages of a Pipeline build
+	PipelinesDescribeURL = "%s/%d/wfapi/describe"
+	// PipelinesNodeDescribeURL is the URL to describe a stage or flow node of a Pipeline build
+	PipelinesNodeDescribeURL = "%s/%d/execution/node/%s/wfapi/describe"
+	// PipelinesNodeLogURL is the URL to get the log of a flow node of a Pipeline build
+	PipelinesNodeLogURL = "%s/%d/execution/node/%s/wfapi/log"
+	// PipelinesPendingInputsURL is the URL to list the input steps a Pipeline build waits on
+	PipelinesPendingInputsURL = "%s/%d/wfapi/pendingInputActions"
+	// PipelinesInputProceedURL is the URL to approve an input step with parameters
+	PipelinesInputProceedURL = "%s/%d/input/%s/proceed"
+	// PipelinesInputProceedEmptyURL is the URL to approve an input step without parameters
+	PipelinesInputProceedEmptyURL = "%s/%d/input/%s/proceedEmpty"
+	// PipelinesInputAbortURL is the URL to abort an input step
+	PipelinesInputAbortURL = "%s/%d/input/%s/abort"
+	// PipelinesReplayURL is the URL of the replay page of a Pipeline build
+	PipelinesReplayURL = "%s/%d/replay/"
+	// PipelinesReplayRunURL is the URL to replay a Pipeline build with modified scripts
+	PipelinesReplayRunURL = "%s/%d/replay/run"
+	// PipelinesReplayRebuildURL is the URL to replay a Pipeline build with unmodified scripts
+	PipelinesReplayRebuildURL = "%s/%d/replay/rebuild"
+	// PipelinesRestartURL is the URL of the restart from stage page of a declarative Pipeline build
+	PipelinesRestartURL = "%s/%d/restart/"
+	// PipelinesRestartRunURL is the URL to restart a declarative Pipeline build from a stage
+	PipelinesRestartRunURL = "%s/%d/restart/restart"
+)
+
+// replayMainScript is the form field of the main script on the replay page.
+const replayMainScript = "mainScript"
+
+var (
+	// replayScriptField matches the script editors of the replay page.
+	replayScriptField = regexp.MustCompile(`(?s)<textarea[^>]*\sname="_\.([^"]+)"[^>]*>(.*?)</textarea>`)
+	// restartStageSelect matches the stage selection of the restart from stage page.
+	restartStageSelect = regexp.MustCompile(`(?s)<select[^>]*\sname="stageName"[^>]*>(.*?)</select>`)
+	// restartStageOption matches the stages offered by the stage selection.
+	restartStageOption = regexp.MustCompile(`<option[^>]*\svalue="([^"]*)"`)
+)
+
+// PipelineStatus represents the status of a Pipeline build, stage or flow node.
+type PipelineStatus string
+
+const (
+	// PipelineStatusSuccess is the status of a successful run
+	PipelineStatusSuccess PipelineStatus = "SUCCESS"
+	// PipelineStatusUnstable is the status of an unstable run
+	PipelineStatusUnstable PipelineStatus = "UNSTABLE"
+	// PipelineStatusFailed is the status of a failed run
+	PipelineStatusFailed PipelineStatus = "FAILED"
+	// PipelineStatusAborted is the status of an aborted run
+	PipelineStatusAborted PipelineStatus = "ABORTED"
+	// PipelineStatusInProgress is the status of a running run
+	PipelineStatusInProgress PipelineStatus = "IN_PROGRESS"
+	// PipelineStatusPausedPendingInput is the status of a run waiting for an input step
+	PipelineStatusPausedPendingInput PipelineStatus = "PAUSED_PENDING_INPUT"
+	// PipelineStatusNotExecuted is the status of a skipped stage
+	PipelineStatusNotExecuted PipelineStatus = "NOT_EXECUTED"
+)
+
+// WorkflowJob represents the config.xml of a Jenkins Pipeline job.
+// Elements it does not model, e.g. properties with the job parameters and triggers,
+// are kept in Unknown, so a decoded job can be updated without losing them.
+type WorkflowJob struct {
+	XMLName xml.Name `xml:"flow-definition"`
+	Plugin  string   `xml:"plugin,attr,omitempty"`
+
+	Description      string         `xml:"description"`
+	KeepDependencies bool           `xml:"keepDependencies"`
+	Disabled         bool           `xml:"disabled,omitempty"`
+	Definition       FlowDefinition `xml:"definition"`
+
+	Unknown []RawElement `xml:",any"`
+}
+
+// NewWorkflowJob returns a Pipeline job with the given definition.
+func NewWorkflowJob(description string, definition FlowDefinition) *WorkflowJob {
+	return &WorkflowJob{
+		Plugin:      "workflow-job",
+		Description: description,
+		Definition:  definition,
+	}
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+// It decodes the XML child definition node into the corresponding FlowDefinition.
+func (j *WorkflowJob) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Alias WorkflowJob // avoids recursive unmarshal
+	v := &struct {
+		Definition struct {
+			InnerXML []byte `xml:",innerxml"`   // Stores inner XML of the <definition> element
+			Class    string `xml:"class,attr"`  // Stores the class name from the <class> attribute
+			Plugin   string `xml:"plugin,attr"` // Stores the plugin from the <plugin> attribute
+		} `xml:"definition"`
+		*Alias
+	}{
+		Alias: (*Alias)(j),
+	}
+
+	if err := d.DecodeElement(v, &start); err != nil {
+		return err
+	}
+
+	// Converts InnerXML to a valid XMl document
+	definitionXML := []byte(fmt.Sprintf("<root>%s</root>", v.Definition.InnerXML))
+
+	switch v.Definition.Class {
+	case "org.jenkinsci.plugins.workflow.cps.CpsFlowDefinition":
+		j.Definition = &CpsFlowDefinition{
+			StaplerClass: v.Definition.Class,
+			Plugin:       v.Definition.Plugin,
+		}
+		err := xml.Unmarshal(definitionXML, j.Definition)
+		if err != nil {
+			return err
+		}
+	case "org.jenkinsci.plugins.workflow.cps.CpsScmFlowDefinition":
+		j.Definition = &CpsScmFlowDefinition{
+			StaplerClass: v.Definition.Class,
+			Plugin:       v.Definition.Plugin,
+		}
+		err := xml.Unmarshal(definitionXML, j.Definition)
+		if err != nil {
+			return err
+		}
+	case "":
+	default:
+		// Keeps definitions of unknown classes verbatim, so they survive an update.
+		j.Definition = &RawFlowDefinition{
+			StaplerClass: v.Definition.Class,
+			Plugin:       v.Definition.Plugin,
+			InnerXML:     string(v.Definition.InnerXML),
+		}
+	}
+
+	return nil
+}
+
+// FlowDefinition is the interface for all Pipeline definitions.
+type FlowDefinition interface{}
+
+// RawFlowDefinition represents a Pipeline definition of a class this package does not model,
+// e.g. one of the Multibranch or Pipeline as YAML plugins.
+type RawFlowDefinition struct {
+	StaplerClass string `xml:"class,attr"`
+	Plugin       string `xml:"plugin,attr,omitempty"`
+	InnerXML     string `xml:",innerxml"`
+}
+
+// CpsFlowDefinition represents a Pipeline defined by an inline script.
+type CpsFlowDefinition struct {
+	StaplerClass string `xml:"class,attr"`
+	Plugin       string `xml:"plugin,attr,omitempty"`
+
+	Script  string `xml:"script"`
+	Sandbox bool   `xml:"sandbox"`
+
+	Unknown []RawElement `xml:",any"`
+}
+
+// NewCpsFlowDefinition returns a Pipeline definition running the given script.
+// Scripts outside the Groovy sandbox need to be approved by an administrator.
+func NewCpsFlowDefinition(script string, sandbox bool) *CpsFlowDefinition {
+	return &CpsFlowDefinition{
+		StaplerClass: "org.jenkinsci.plugins.workflow.cps.CpsFlowDefinition",
+		Plugin:       "workflow-cps",
+		Script:       script,
+		Sandbox:      sandbox,
+	}
+}
+
+// CpsScmFlowDefinition represents a Pipeline defined by a script checked out from SCM.
+type CpsScmFlowDefinition struct {
+	StaplerClass string `xml:"class,attr"`
+	Plugin       string `xml:"plugin,attr,omitempty"`
+
+	SCM         *GitSCM `xml:"scm"`
+	ScriptPath  string  `xml:"scriptPath"`
+	Lightweight bool    `xml:"lightweight"`
+
+	Unknown []RawElement `xml:",any"`
+}
+
+// NewCpsScmFlowDefinition returns a Pipeline definition running the script at scriptPath of a repository.
+// The script is fetched with a lightweight checkout.
+func NewCpsScmFlowDefinition(scm *GitSCM, scriptPath string) *CpsScmFlowDefinition {
+	return &CpsScmFlowDefinition{
+		StaplerClass: "org.jenkinsci.plugins.workflow.cps.CpsScmFlowDefinition",
+		Plugin:       "workflow-cps",
+		SCM:          scm,
+		ScriptPath:   scriptPath,
+		Lightweight:  true,
+	}
+}
+
+// GitSCM represents a Git repository configuration of the Git plugin.
+// Elements it does not model, e.g. the extensions and the Git tool, are kept in Unknown.
+type GitSCM struct {
+	StaplerClass string `xml:"class,attr"`
+	Plugin       string `xml:"plugin,attr,omitempty"`
+
+	ConfigVersion                     int                `xml:"configVersion,omitempty"`
+	UserRemoteConfigs                 []UserRemoteConfig `xml:"userRemoteConfigs>hudson.plugins.git.UserRemoteConfig"`
+	Branches                          []BranchSpec       `xml:"branches>hudson.plugins.git.BranchSpec"`
+	DoGenerateSubmoduleConfigurations bool               `xml:"doGenerateSubmoduleConfigurations,omitempty"`
+
+	Unknown []RawElement `xml:",any"`
+}
+
+// NewGitSCM returns a Git repository configuration building the given branches, e.g. "*/main".
+// The credentials ID may be empty for public repositories.
+func NewGitSCM(url, credentialsID string, branches ...string) *GitSCM {
+	scm := &GitSCM{
+		StaplerClass:      "hudson.plugins.git.GitSCM",
+		Plugin:            "git",
+		ConfigVersion:     2,
+		UserRemoteConfigs: []UserRemoteConfig{{URL: url, CredentialsID: credentialsID}},
+	}
+
+	for _, branch := range branches {
+		scm.Branches = append(scm.Branches, BranchSpec{Name: branch})
+	}
+
+	return scm
+}
+
+// UserRemoteConfig represents a Git remote.
+type UserRemoteConfig struct {
+	Name          string `xml:"name,omitempty"`
+	Refspec       string `xml:"refspec,omitempty"`
+	URL           string `xml:"url"`
+	CredentialsID string `xml:"credentialsId,omitempty"`
+}
+
+// BranchSpec represents a Git branch specifier, e.g. "*/main".
+type BranchSpec struct {
+	Name string `xml:"name"`
+}
+
+// PipelineRun represents the stage view of a Pipeline build.
+type PipelineRun struct {
+	ID                  string          `json:"id"`
+	Name                string          `json:"name"`
+	Status              PipelineStatus  `json:"status"`
+	StartTimeMillis     int64           `json:"startTimeMillis"`
+	EndTimeMillis       int64           `json:"endTimeMillis"`
+	DurationMillis      int64           `json:"durationMillis"`
+	QueueDurationMillis int64           `json:"queueDurationMillis"`
+	PauseDurationMillis int64           `json:"pauseDurationMillis"`
+	Stages              []PipelineStage `json:"stages"`
+}
+
+// FailedStage returns the first stage that failed, or nil if no stage failed.
+func (r *PipelineRun) FailedStage() *PipelineStage {
+	for i := range r.Stages {
+		if r.Stages[i].Status == PipelineStatusFailed {
+			return &r.Stages[i]
+		}
+	}
+
+	return nil
+}
+
+// PipelineStage represents a stage of a Pipeline build.
+// The flow nodes of a stage are only included by PipelinesService.DescribeStage.
+type PipelineStage struct {
+	ID                  string             `json:"id"`
+	Name                string             `json:"name"`
+	ExecNode            string             `json:"execNode"`
+	Status              PipelineStatus     `json:"status"`
+	StartTimeMillis     int64              `json:"startTimeMillis"`
+	DurationMillis      int64              `json:"durationMillis"`
+	PauseDurationMillis int64              `json:"pauseDurationMillis"`
+	Error               *PipelineError     `json:"error"`
+	StageFlowNodes      []PipelineFlowNode `json:"stageFlowNodes"`
+}
+
+// PipelineFlowNode represents a step of a Pipeline stage.
+type PipelineFlowNode struct {
+	ID                   string         `json:"id"`
+	Name                 string         `json:"name"`
+	ExecNode             string         `json:"execNode"`
+	Status               PipelineStatus `json:"status"`
+	ParameterDescription string         `json:"parameterDescription"`
+	StartTimeMillis      int64          `json:"startTimeMillis"`
+	DurationMillis       int64          `json:"durationMillis"`
+	PauseDurationMillis  int64          `json:"pauseDurationMillis"`
+	ParentNodes          []string       `json:"parentNodes"`
+	Error                *PipelineError `json:"error"`
+}
+
+// PipelineError represents the error a stage or flow node failed with.
+type PipelineError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+// PipelineNodeLog represents the log of a flow node.
+type PipelineNodeLog struct {
+	NodeID     string         `json:"nodeId"`
+	NodeStatus PipelineStatus `json:"nodeStatus"`
+	Length     int64          `json:"length"`
+	HasMore    bool           `json:"hasMore"`
+	Text       string         `json:"text"`
+	ConsoleURL string         `json:"consoleUrl"`
+}
+
+// PendingInput represents an input step a Pipeline build is waiting on.
+type PendingInput struct {
+	ID                  string               `json:"id"`
+	Message             string               `json:"message"`
+	ProceedText         string               `json:"proceedText"`
+	ProceedURL          string               `json:"proceedUrl"`
+	AbortURL            string               `json:"abortUrl"`
+	RedirectApprovalURL string               `json:"redirectApprovalUrl"`
+	Inputs              []InputParameterSpec `json:"inputs"`
+}
+
+// InputParameterSpec represents a parameter requested by an input step.
+type InputParameterSpec struct {
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Definition  map[string]interface{} `json:"definition"`
+}
+
+// inputParameter represents a parameter value submitted to an input step.
+type inputParameter struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+// ReplayScripts represents the scripts a Pipeline build was run with.
+type ReplayScripts struct {
+	// Main is the Pipeline script.
+	Main string
+	// Loaded maps the form field names of the scripts loaded by the Pipeline, e.g. with load, to their content.
+	Loaded map[string]string
+}
+
+// PipelinesService handles communication with the Pipeline build related methods of the Jenkins API.
+// It uses the wfapi endpoints of the Pipeline Stage View plugin.
+type PipelinesService service
+
+// Describe returns the stages of a Pipeline build.
+func (s *PipelinesService) Describe(ctx context.Context, job string, number int) (*PipelineRun, *http.Response, error) {
+	var run PipelineRun
+	resp, err := s.client.getJSON(ctx, fmt.Sprintf(PipelinesDescribeURL, JobPath(job).URLPath(), number), &run)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &run, resp, nil
+}
+
+// DescribeStage returns a stage of a Pipeline build including its flow nodes.
+func (s *PipelinesService) DescribeStage(ctx context.Context, job string, number int, stageID string) (*PipelineStage, *http.Response, error) {
+	var stage PipelineStage
+	resp, err := s.client.getJSON(ctx, fmt.Sprintf(PipelinesNodeDescribeURL, JobPath(job).URLPath(), number, url.PathEscape(stageID)), &stage)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &stage, resp, nil
+}
+
+// NodeLog returns the log of a flow node of a Pipeline build.
+// Long logs are truncated by Jenkins, see PipelineNodeLog.HasMore.
+func (s *PipelinesService) NodeLog(ctx context.Context, job string, number int, nodeID string) (*PipelineNodeLog, *http.Response, error) {
+	var log PipelineNodeLog
+	resp, err := s.client.getJSON(ctx, fmt.Sprintf(PipelinesNodeLogURL, JobPath(job).URLPath(), number, url.PathEscape(nodeID)), &log)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &log, resp, nil
+}
+
+// StageLog returns the concatenated logs of all flow nodes of a stage.
+func (s *PipelinesService) StageLog(ctx context.Context, job string, number int, stageID string) (string, *http.Response, error) {
+	stage, resp, err := s.DescribeStage(ctx, job, number, stageID)
+	if err != nil {
+		return "", resp, err
+	}
+
+	var b strings.Builder
+	for _, node := range stage.StageFlowNodes {
+		log, resp, err := s.NodeLog(ctx, job, number, node.ID)
+		if err != nil {
+			return "", resp, err
+		}
+		b.WriteString(log.Text)
+	}
+
+	return b.String(), resp, nil
+}
+
+// PendingInputs returns the input steps a Pipeline build is waiting on.
+func (s *PipelinesService) PendingInputs(ctx context.Context, job string, number int) ([]PendingInput, *http.Response, error) {
+	var inputs []PendingInput
+	resp, err := s.client.getJSON(ctx, fmt.Sprintf(PipelinesPendingInputsURL, JobPath(job).URLPath(), number), &inputs)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return inputs, resp, nil
+}
+
+// ApproveInput approves an input step, see PendingInput.ID, with the given parameter values.
+// Only string, boolean and choice parameters are supported.
+func (s *PipelinesService) ApproveInput(ctx context.Context, job string, number int, inputID string, params ...BuildParameter) (*http.Response, error) {
+	jobURL := JobPath(job).URLPath()
+
+	if len(params) == 0 {
+		return s.client.post(ctx, fmt.Sprintf(PipelinesInputProceedEmptyURL, jobURL, number, url.PathEscape(inputID)), nil)
+	}
+
+	values := make([]inputParameter, 0, len(params))
+	for _, param := range params {
+		switch p := dereferenceParameter(param).(type) {
+		case StringParameter:
+			values = append(values, inputParameter{Name: p.Name, Value: p.Value})
+		case BooleanParameter:
+			values = append(values, inputParameter{Name: p.Name, Value: p.Value})
+		case ChoiceParameter:
+			values = append(values, inputParameter{Name: p.Name, Value: p.Value})
+		default:
+			return nil, fmt.Errorf("unsupported input parameter type %T", p)
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"parameter": values})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.client.postForm(ctx, fmt.Sprintf(PipelinesInputProceedURL, jobURL, number, url.PathEscape(inputID)), url.Values{
+		"json": {string(body)},
+	})
+}
+
+// AbortInput rejects an input step, which aborts the Pipeline build.
+func (s *PipelinesService) AbortInput(ctx context.Context, job string, number int, inputID string) (*http.Response, error) {
+	return s.client.post(ctx, fmt.Sprintf(PipelinesInputAbortURL, JobPath(job).URLPath(), number, url.PathEscape(inputID)), nil)
+}
+
+// ReplayScripts returns the scripts a Pipeline build was run with, as shown on the replay page.
+// Jenkins offers no JSON API for them, the scripts are read from the page form.
+func (s *PipelinesService) ReplayScripts(ctx context.Context, job string, number int) (*ReplayScripts, *http.Response, error) {
+	page, resp, err := s.client.getBody(ctx, fmt.Sprintf(PipelinesReplayURL, JobPath(job).URLPath(), number))
+	if err != nil {
+		return nil, resp, err
+	}
+
+	scripts := &ReplayScripts{Loaded: make(map[string]string)}
+	found := false
+
+	for _, match := range replayScriptField.FindAllSubmatch(page, -1) {
+		// Browsers drop the newline directly following the textarea start tag.
+		name := string(match[1])
+		script := strings.TrimPrefix(html.UnescapeString(string(match[2])), "\n")
+		if name == replayMainScript {
+			scripts.Main = script
+			found = true
+			continue
+		}
+		scripts.Loaded[name] = script
+	}
+
+	if !found {
+		return nil, resp, fmt.Errorf("no replayable script found for build %d of %s", number, job)
+	}
+
+	return scripts, resp, nil
+}
+
+// Replay runs a Pipeline build again with modified scripts, like the Replay action in the UI.
+// Jenkins rejects a replay missing any of the loaded scripts of the build, so the scripts missing
+// from scripts.Loaded are filled in from ReplayScripts and replayed unchanged.
+func (s *PipelinesService) Replay(ctx context.Context, job string, number int, scripts *ReplayScripts) (*http.Response, error) {
+	current, resp, err := s.ReplayScripts(ctx, job, number)
+	if err != nil {
+		return resp, err
+	}
+
+	form := map[string]string{replayMainScript: scripts.Main}
+	values := url.Values{replayMainScript: {scripts.Main}}
+
+	for name, script := range current.Loaded {
+		if modified, ok := scripts.Loaded[name]; ok {
+			script = modified
+		}
+		form[name] = script
+		values.Set(name, script)
+	}
+
+	for name := range scripts.Loaded {
+		if _, ok := current.Loaded[name]; !ok {
+			return nil, fmt.Errorf("build %d of %s has no loaded script %s", number, job, name)
+		}
+	}
+
+	body, err := json.Marshal(form)
+	if err != nil {
+		return nil, err
+	}
+	values.Set("json", string(body))
+
+	return s.client.postForm(ctx, fmt.Sprintf(PipelinesReplayRunURL, JobPath(job).URLPath(), number), values)
+}
+
+// Rebuild runs a Pipeline build again with the scripts it was run with.
+func (s *PipelinesService) Rebuild(ctx context.Context, job string, number int) (*http.Response, error) {
+	return s.client.post(ctx, fmt.Sprintf(PipelinesReplayRebuildURL, JobPath(job).URLPath(), number), nil)
+}
+
+// RestartableStages returns the stages a declarative Pipeline build can be restarted from.
+// Jenkins offers no JSON API for them, the stages are read from the page form.
+func (s *PipelinesService) RestartableStages(ctx context.Context, job string, number int) ([]string, *http.Response, error) {
+	page, resp, err := s.client.getBody(ctx, fmt.Sprintf(PipelinesRestartURL, JobPath(job).URLPath(), number))
+	if err != nil {
+		return nil, resp, err
+	}
+
+	selection := restartStageSelect.FindSubmatch(page)
+	if selection == nil {
+		return nil, resp, fmt.Errorf("build %d of %s cannot be restarted from a stage", number, job)
+	}
+
+	var stages []string
+	for _, match := range restartStageOption.FindAllSubmatch(selection[1], -1) {
+		stages = append(stages, html.UnescapeString(string(match[1])))
+	}
+
+	return stages, resp, nil
+}
+
+// RestartFromStage runs a declarative Pipeline build again, skipping the stages before the given one.
+func (s *PipelinesService) RestartFromStage(ctx context.Context, job string, number int, stage string) (*http.Response, error) {
+	body, err := json.Marshal(map[string]string{"stageName": stage})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.client.postForm(ctx, fmt.Sprintf(PipelinesRestartRunURL, JobPath(job).URLPath(), number), url.Values{
+		"stageName": {stage},
+		"json":      {string(body)},
+	})
+}
@@ -0,0 +1,475 @@
+package jenkins
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+func (s *Suite) TestWorkflowJobMarshalCpsFlowDefinition() {
+	job := NewWorkflowJob("deploy", NewCpsFlowDefinition("node { echo 'hi' }", true))
+
+	b, err := xml.Marshal(job)
+	s.NoError(err)
+	s.Equal(`<flow-definition plugin="workflow-job"><description>deploy</description><keepDependencies>false</keepDependencies>`+
+		`<definition class="org.jenkinsci.plugins.workflow.cps.CpsFlowDefinition" plugin="workflow-cps">`+
+		`<script>node { echo &#39;hi&#39; }</script><sandbox>true</sandbox></definition></flow-definition>`, string(b))
+
+	var decoded WorkflowJob
+	s.NoError(xml.Unmarshal(b, &decoded))
+	s.Equal(job.Definition, decoded.Definition)
+	s.Equal("deploy", decoded.Description)
+}
+
+func (s *Suite) TestWorkflowJobMarshalCpsScmFlowDefinition() {
+	job := NewWorkflowJob("", NewCpsScmFlowDefinition(NewGitSCM("https://github.com/yarlson/go-jenkins.git", "github", "*/main"), "Jenkinsfile"))
+
+	b, err := xml.Marshal(job)
+	s.NoError(err)
+	s.Contains(string(b), `<scm class="hudson.plugins.git.GitSCM" plugin="git"><configVersion>2</configVersion>`+
+		`<userRemoteConfigs><hudson.plugins.git.UserRemoteConfig><url>https://github.com/yarlson/go-jenkins.git</url><credentialsId>github</credentialsId></hudson.plugins.git.UserRemoteConfig></userRemoteConfigs>`+
+		`<branches><hudson.plugins.git.BranchSpec><name>*/main</name></hudson.plugins.git.BranchSpec></branches>`)
+
+	var decoded WorkflowJob
+	s.NoError(xml.Unmarshal(b, &decoded))
+	s.Equal(job.Definition, decoded.Definition)
+}
+
+func (s *Suite) TestWorkflowJobUnmarshal() {
+	inputXML := `<?xml version='1.0' encoding='UTF-8'?>
+<flow-definition plugin="workflow-job@1436.vfa_244484591f">
+  <actions/>
+  <description>deploy</description>
+  <keepDependencies>false</keepDependencies>
+  <properties/>
+  <definition class="org.jenkinsci.plugins.workflow.cps.CpsScmFlowDefinition" plugin="workflow-cps@3894.vd0f0248b_a_fc4">
+    <scm class="hudson.plugins.git.GitSCM" plugin="git@5.2.2">
+      <configVersion>2</configVersion>
+      <userRemoteConfigs>
+        <hudson.plugins.git.UserRemoteConfig>
+          <url>git@github.com:team/service.git</url>
+          <credentialsId>deploy-key</credentialsId>
+        </hudson.plugins.git.UserRemoteConfig>
+      </userRemoteConfigs>
+      <branches>
+        <hudson.plugins.git.BranchSpec>
+          <name>*/main</name>
+        </hudson.plugins.git.BranchSpec>
+        <hudson.plugins.git.BranchSpec>
+          <name>*/release</name>
+        </hudson.plugins.git.BranchSpec>
+      </branches>
+      <doGenerateSubmoduleConfigurations>false</doGenerateSubmoduleConfigurations>
+      <submoduleCfg class="empty-list"/>
+      <extensions/>
+    </scm>
+    <scriptPath>ci/Jenkinsfile</scriptPath>
+    <lightweight>true</lightweight>
+  </definition>
+  <triggers/>
+  <disabled>false</disabled>
+</flow-definition>`
+
+	var job WorkflowJob
+	s.NoError(xml.Unmarshal([]byte(inputXML), &job))
+
+	definition := job.Definition.(*CpsScmFlowDefinition)
+	s.Equal("org.jenkinsci.plugins.workflow.cps.CpsScmFlowDefinition", definition.StaplerClass)
+	s.Equal("workflow-cps@3894.vd0f0248b_a_fc4", definition.Plugin)
+	s.Equal("ci/Jenkinsfile", definition.ScriptPath)
+	s.True(definition.Lightweight)
+	s.Equal("hudson.plugins.git.GitSCM", definition.SCM.StaplerClass)
+	s.Equal("deploy-key", definition.SCM.UserRemoteConfigs[0].CredentialsID)
+	s.Equal([]BranchSpec{{Name: "*/main"}, {Name: "*/release"}}, definition.SCM.Branches)
+}
+
+func (s *Suite) TestWorkflowJobKeepsUnknownSCMElements() {
+	inputXML := `<flow-definition plugin="workflow-job@1436.vfa_244484591f">
+  <description></description>
+  <keepDependencies>false</keepDependencies>
+  <definition class="org.jenkinsci.plugins.workflow.cps.CpsScmFlowDefinition" plugin="workflow-cps@3894.vd0f0248b_a_fc4">
+    <scm class="hudson.plugins.git.GitSCM" plugin="git@5.2.2">
+      <configVersion>2</configVersion>
+      <userRemoteConfigs>
+        <hudson.plugins.git.UserRemoteConfig>
+          <url>git@github.com:team/service.git</url>
+        </hudson.plugins.git.UserRemoteConfig>
+      </userRemoteConfigs>
+      <branches>
+        <hudson.plugins.git.BranchSpec>
+          <name>*/main</name>
+        </hudson.plugins.git.BranchSpec>
+      </branches>
+      <gitTool>jgit</gitTool>
+      <extensions>
+        <hudson.plugins.git.extensions.impl.CleanBeforeCheckout/>
+      </extensions>
+    </scm>
+    <scriptPath>Jenkinsfile</scriptPath>
+    <lightweight>false</lightweight>
+  </definition>
+</flow-definition>`
+
+	var job WorkflowJob
+	s.NoError(xml.Unmarshal([]byte(inputXML), &job))
+
+	b, err := xml.Marshal(&job)
+	s.NoError(err)
+	s.Contains(string(b), `<gitTool>jgit</gitTool>`)
+	s.Contains(string(b), `<extensions>`)
+	s.Contains(string(b), `<hudson.plugins.git.extensions.impl.CleanBeforeCheckout/>`)
+	s.NotContains(string(b), "doGenerateSubmoduleConfigurations")
+
+	var again WorkflowJob
+	s.NoError(xml.Unmarshal(b, &again))
+	s.Equal(job.Definition, again.Definition)
+}
+
+func (s *Suite) TestWorkflowJobKeepsUnknownElements() {
+	inputXML := `<flow-definition plugin="workflow-job@1436.vfa_244484591f">
+  <description>deploy</description>
+  <keepDependencies>false</keepDependencies>
+  <properties>
+    <hudson.model.ParametersDefinitionProperty>
+      <parameterDefinitions>
+        <hudson.model.StringParameterDefinition>
+          <name>VERSION</name>
+        </hudson.model.StringParameterDefinition>
+      </parameterDefinitions>
+    </hudson.model.ParametersDefinitionProperty>
+  </properties>
+  <definition class="org.jenkinsci.plugins.pipeline.as.yaml.PipelineAsYamlScriptFlowDefinition" plugin="pipeline-as-yaml@0.16">
+    <script>pipeline: {}</script>
+  </definition>
+  <triggers>
+    <hudson.triggers.TimerTrigger>
+      <spec>H 2 * * *</spec>
+    </hudson.triggers.TimerTrigger>
+  </triggers>
+</flow-definition>`
+
+	var job WorkflowJob
+	s.NoError(xml.Unmarshal([]byte(inputXML), &job))
+	s.Equal(&RawFlowDefinition{
+		StaplerClass: "org.jenkinsci.plugins.pipeline.as.yaml.PipelineAsYamlScriptFlowDefinition",
+		Plugin:       "pipeline-as-yaml@0.16",
+		InnerXML:     "\n    <script>pipeline: {}</script>\n  ",
+	}, job.Definition)
+
+	job.Description = "deploy nightly"
+	b, err := xml.Marshal(&job)
+	s.NoError(err)
+	s.Contains(string(b), `<definition class="org.jenkinsci.plugins.pipeline.as.yaml.PipelineAsYamlScriptFlowDefinition" plugin="pipeline-as-yaml@0.16">
+    <script>pipeline: {}</script>
+  </definition>`)
+	s.Contains(string(b), `<name>VERSION</name>`)
+	s.Contains(string(b), `<spec>H 2 * * *</spec>`)
+
+	var again WorkflowJob
+	s.NoError(xml.Unmarshal(b, &again))
+	s.Equal(job.Definition, again.Definition)
+	s.Equal(job.Unknown, again.Unknown)
+}
+
+func (s *Suite) TestJobsServiceCreateWorkflowJob() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(JobsCreateURL, "/job/team"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		body, err := io.ReadAll(r.Body)
+		s.NoError(err)
+		s.Contains(string(body), `<definition class="org.jenkinsci.plugins.workflow.cps.CpsFlowDefinition" plugin="workflow-cps">`)
+	})
+
+	_, err = client.Jobs.Create(context.Background(), "team/deploy", NewWorkflowJob("", NewCpsFlowDefinition("echo 'hi'", true)))
+	s.NoError(err)
+}
+
+func (s *Suite) TestPipelinesServiceDescribe() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(PipelinesDescribeURL, "/job/team/job/deploy", 7), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(`{
+  "id": "7", "name": "#7", "status": "FAILED", "startTimeMillis": 1700000000000, "durationMillis": 42000,
+  "stages": [
+    {"id": "6", "name": "Build", "execNode": "", "status": "SUCCESS", "durationMillis": 30000},
+    {"id": "15", "name": "Deploy", "execNode": "", "status": "FAILED", "durationMillis": 12000,
+     "error": {"message": "script returned exit code 1", "type": "hudson.AbortException"}},
+    {"id": "22", "name": "Verify", "status": "NOT_EXECUTED"}
+  ]
+}`))
+		s.NoError(err)
+	})
+
+	run, _, err := client.Pipelines.Describe(context.Background(), "team/deploy", 7)
+	s.NoError(err)
+	s.Equal(PipelineStatusFailed, run.Status)
+	s.Len(run.Stages, 3)
+	s.Equal("Deploy", run.FailedStage().Name)
+	s.Equal("script returned exit code 1", run.FailedStage().Error.Message)
+
+	run.Stages[1].Status = PipelineStatusSuccess
+	s.Nil(run.FailedStage())
+
+	_, _, err = client.Pipelines.Describe(context.Background(), "team/deploy", 8)
+	s.Error(err)
+}
+
+func (s *Suite) TestPipelinesServiceStageLog() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(PipelinesNodeDescribeURL, "/job/deploy", 7, "15"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(`{"id": "15", "name": "Deploy", "status": "FAILED", "stageFlowNodes": [
+			{"id": "16", "name": "Shell Script", "status": "SUCCESS", "parameterDescription": "make", "parentNodes": ["15"]},
+			{"id": "17", "name": "Shell Script", "status": "FAILED", "parameterDescription": "make deploy", "parentNodes": ["16"]}
+		]}`))
+		s.NoError(err)
+	})
+	for _, id := range []string{"16", "17"} {
+		id := id
+		s.mux.HandleFunc(fmt.Sprintf(PipelinesNodeLogURL, "/job/deploy", 7, id), func(w http.ResponseWriter, r *http.Request) {
+			s.testMethod(r, "GET")
+			_, err := w.Write([]byte(`{"nodeId": "` + id + `", "nodeStatus": "SUCCESS", "length": 8, "hasMore": false, "text": "node ` + id + `\n"}`))
+			s.NoError(err)
+		})
+	}
+
+	stage, _, err := client.Pipelines.DescribeStage(context.Background(), "deploy", 7, "15")
+	s.NoError(err)
+	s.Len(stage.StageFlowNodes, 2)
+	s.Equal("make deploy", stage.StageFlowNodes[1].ParameterDescription)
+
+	log, _, err := client.Pipelines.NodeLog(context.Background(), "deploy", 7, "16")
+	s.NoError(err)
+	s.Equal("node 16\n", log.Text)
+
+	text, _, err := client.Pipelines.StageLog(context.Background(), "deploy", 7, "15")
+	s.NoError(err)
+	s.Equal("node 16\nnode 17\n", text)
+
+	_, _, err = client.Pipelines.StageLog(context.Background(), "deploy", 7, "99")
+	s.Error(err)
+}
+
+func (s *Suite) TestPipelinesServicePendingInputs() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(PipelinesPendingInputsURL, "/job/deploy", 7), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(`[{
+  "id": "Promote", "proceedText": "Deploy", "message": "Deploy to production?",
+  "inputs": [{"type": "ChoiceParameterDefinition", "name": "REGION", "description": "", "definition": {"choices": ["eu", "us"]}}],
+  "proceedUrl": "/job/deploy/7/wfapi/inputSubmit?inputId=Promote",
+  "abortUrl": "/job/deploy/7/input/Promote/abort",
+  "redirectApprovalUrl": "/job/deploy/7/input/"
+}]`))
+		s.NoError(err)
+	})
+
+	inputs, _, err := client.Pipelines.PendingInputs(context.Background(), "deploy", 7)
+	s.NoError(err)
+	s.Len(inputs, 1)
+	s.Equal("Promote", inputs[0].ID)
+	s.Equal("REGION", inputs[0].Inputs[0].Name)
+
+	_, _, err = client.Pipelines.PendingInputs(context.Background(), "deploy", 8)
+	s.Error(err)
+}
+
+func (s *Suite) TestPipelinesServiceApproveInput() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(PipelinesInputProceedURL, "/job/deploy", 7, "Promote"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("crumb", r.Header.Get("crumb"))
+		s.JSONEq(`{"parameter":[{"name":"REGION","value":"eu"},{"name":"CANARY","value":true}]}`, r.FormValue("json"))
+	})
+	s.mux.HandleFunc(fmt.Sprintf(PipelinesInputProceedEmptyURL, "/job/deploy", 7, "Promote"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+	})
+
+	_, err = client.Pipelines.ApproveInput(context.Background(), "deploy", 7, "Promote",
+		ChoiceParameter{Name: "REGION", Value: "eu"},
+		BooleanParameter{Name: "CANARY", Value: true},
+	)
+	s.NoError(err)
+
+	_, err = client.Pipelines.ApproveInput(context.Background(), "deploy", 7, "Promote")
+	s.NoError(err)
+
+	_, err = client.Pipelines.ApproveInput(context.Background(), "deploy", 7, "Promote", FileParameter{Name: "FILE"})
+	s.Error(err)
+}
+
+func (s *Suite) TestPipelinesServiceAbortInput() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(PipelinesInputAbortURL, "/job/deploy", 7, "Promote"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+	})
+
+	_, err = client.Pipelines.AbortInput(context.Background(), "deploy", 7, "Promote")
+	s.NoError(err)
+
+	_, err = client.Pipelines.AbortInput(context.Background(), "deploy", 7, "Other")
+	s.Error(err)
+}
+
+func (s *Suite) TestPipelinesServiceReplayScripts() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(PipelinesReplayURL, "/job/deploy", 7), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(`<html><body><form method="post" action="run" name="config">
+<textarea class="secure" name="_.mainScript" id="workflow-editor-1">
+node { sh &#39;make &amp;&amp; make deploy&#39; }</textarea>
+<textarea name="_.Script1" class="secure">echo &quot;loaded&quot;</textarea>
+</form></body></html>`))
+		s.NoError(err)
+	})
+
+	scripts, _, err := client.Pipelines.ReplayScripts(context.Background(), "deploy", 7)
+	s.NoError(err)
+	s.Equal("node { sh 'make && make deploy' }", scripts.Main)
+	s.Equal(map[string]string{"Script1": `echo "loaded"`}, scripts.Loaded)
+}
+
+func (s *Suite) TestPipelinesServiceReplayScriptsError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(PipelinesReplayURL, "/job/deploy", 7), func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`<html></html>`))
+		s.NoError(err)
+	})
+
+	_, _, err = client.Pipelines.ReplayScripts(context.Background(), "deploy", 7)
+	s.Error(err)
+
+	_, _, err = client.Pipelines.ReplayScripts(context.Background(), "deploy", 8)
+	s.Error(err)
+}
+
+func (s *Suite) TestPipelinesServiceReplay() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(PipelinesReplayURL, "/job/deploy", 7), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(`<form method="post" action="run" name="config">
+<textarea name="_.mainScript">node { load 'a.groovy'; load 'b.groovy' }</textarea>
+<textarea name="_.Script1">echo 0</textarea>
+<textarea name="_.Script2">echo 2</textarea>
+</form>`))
+		s.NoError(err)
+	})
+
+	var posted string
+	s.mux.HandleFunc(fmt.Sprintf(PipelinesReplayRunURL, "/job/deploy", 7), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("crumb", r.Header.Get("crumb"))
+		s.Equal("node {}", r.FormValue("mainScript"))
+		posted = r.FormValue("json")
+	})
+	s.mux.HandleFunc(fmt.Sprintf(PipelinesReplayRebuildURL, "/job/deploy", 7), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+	})
+
+	_, err = client.Pipelines.Replay(context.Background(), "deploy", 7, &ReplayScripts{
+		Main:   "node {}",
+		Loaded: map[string]string{"Script1": "echo 1", "Script2": "echo 3"},
+	})
+	s.NoError(err)
+	s.JSONEq(`{"mainScript":"node {}","Script1":"echo 1","Script2":"echo 3"}`, posted)
+
+	// Loaded scripts left out are replayed unchanged, as Jenkins requires all of them.
+	_, err = client.Pipelines.Replay(context.Background(), "deploy", 7, &ReplayScripts{
+		Main:   "node {}",
+		Loaded: map[string]string{"Script1": "echo 1"},
+	})
+	s.NoError(err)
+	s.JSONEq(`{"mainScript":"node {}","Script1":"echo 1","Script2":"echo 2"}`, posted)
+
+	posted = ""
+	_, err = client.Pipelines.Replay(context.Background(), "deploy", 7, &ReplayScripts{
+		Main:   "node {}",
+		Loaded: map[string]string{"Script3": "echo 3"},
+	})
+	s.EqualError(err, "build 7 of deploy has no loaded script Script3")
+	s.Empty(posted)
+
+	_, err = client.Pipelines.Rebuild(context.Background(), "deploy", 7)
+	s.NoError(err)
+}
+
+func (s *Suite) TestPipelinesServiceRestartFromStage() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(PipelinesRestartURL, "/job/deploy", 7), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		_, err := w.Write([]byte(`<form method="post" action="restart" name="restart">
+<select class="setting-input" name="stageName"><option value="Build">Build</option><option value="Deploy &amp; Verify">Deploy &amp; Verify</option></select>
+</form>`))
+		s.NoError(err)
+	})
+	s.mux.HandleFunc(fmt.Sprintf(PipelinesRestartRunURL, "/job/deploy", 7), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.JSONEq(`{"stageName":"Deploy & Verify"}`, r.FormValue("json"))
+	})
+
+	stages, _, err := client.Pipelines.RestartableStages(context.Background(), "deploy", 7)
+	s.NoError(err)
+	s.Equal([]string{"Build", "Deploy & Verify"}, stages)
+
+	_, err = client.Pipelines.RestartFromStage(context.Background(), "deploy", 7, stages[1])
+	s.NoError(err)
+}
+
+func (s *Suite) TestPipelinesServiceRestartableStagesError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(PipelinesRestartURL, "/job/deploy", 7), func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`<html></html>`))
+		s.NoError(err)
+	})
+
+	_, _, err = client.Pipelines.RestartableStages(context.Background(), "deploy", 7)
+	s.Error(err)
+
+	_, _, err = client.Pipelines.RestartableStages(context.Background(), "deploy", 8)
+	s.Error(err)
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 The go-jenkins AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jenkins
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// QueueListURL is the URL to list the build queue
+	QueueListURL = "/queue/api/json"
+	// QueueItemURL is the URL to get a queue item
+	QueueItemURL = "/queue/item/%d/api/json"
+	// QueueCancelURL is the URL to cancel a queue item
+	QueueCancelURL = "/queue/cancelItem"
+)
+
+// queueLanguage is the language the queue is requested in, so the why-blocked reasons can be parsed.
+const queueLanguage = "en"
+
+// queueBlockedOn matches the label or node name Jenkins quotes in the why-blocked reason of a queue item,
+// e.g. "Waiting for next available executor on ‘linux’".
+var queueBlockedOn = regexp.MustCompile(`‘([^’]*)’`)
+
+// QueueListResponse represents a Jenkins queue list response.
+type QueueListResponse struct {
+	Class string      `json:"_class"`
+	Items []QueueItem `json:"items"`
+}
+
+// QueueItem represents an item of the Jenkins build queue.
+type QueueItem struct {
+	Class        string    `json:"_class"`
+	ID           int64     `json:"id"`
+	Blocked      bool      `json:"blocked"`
+	Buildable    bool      `json:"buildable"`
+	Stuck        bool      `json:"stuck"`
+	Cancelled    bool      `json:"cancelled"`
+	InQueueSince int64     `json:"inQueueSince"`
+	Params       string    `json:"params"`
+	Why          string    `json:"why"`
+	Task         QueueTask `json:"task"`
+	Executable   *BuildRef `json:"executable"`
+}
+
+// QueuedAt returns the time the item entered the queue.
+func (q *QueueItem) QueuedAt() time.Time {
+	return time.UnixMilli(q.InQueueSince)
+}
+
+// Parameters returns the build parameters of the item.
+// Jenkins reports them as newline separated NAME=value pairs.
+func (q *QueueItem) Parameters() map[string]string {
+	params := make(map[string]string)
+	for _, line := range strings.Split(q.Params, "\n") {
+		if name, value, ok := strings.Cut(line, "="); ok {
+			params[name] = value
+		}
+	}
+
+	return params
+}
+
+// Label returns the label or node name the item is waiting for, as quoted in the why-blocked reason.
+// The Jenkins API does not report it otherwise, so this is a best-effort parse of the English reason,
+// which QueueService requests regardless of the locale of Jenkins. It returns an empty string if the
+// reason does not quote a label, e.g. for items blocked by a running build or reasons reported by plugins.
+func (q *QueueItem) Label() string {
+	match := queueBlockedOn.FindStringSubmatch(q.Why)
+	if match == nil {
+		return ""
+	}
+
+	return match[1]
+}
+
+// QueueTask represents the job a queue item was scheduled for.
+type QueueTask struct {
+	Class string `json:"_class"`
+	Name  string `json:"name"`
+	URL   string `json:"url"`
+	Color string `json:"color"`
+}
+
+// BuildRef references a build by its number and URL.
+type BuildRef struct {
+	Class  string `json:"_class"`
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+}
+
+// GroupByLabel groups queue items by the label they are waiting for, see QueueItem.Label.
+func GroupByLabel(items []QueueItem) map[string][]QueueItem {
+	groups := make(map[string][]QueueItem)
+	for _, item := range items {
+		label := item.Label()
+		groups[label] = append(groups[label], item)
+	}
+
+	return groups
+}
+
+// QueueService handles communication with the build queue related methods of the Jenkins API
+type QueueService service
+
+// List returns the items of the build queue.
+func (s *QueueService) List(ctx context.Context) ([]QueueItem, *http.Response, error) {
+	var listResp QueueListResponse
+	resp, err := s.client.getJSON(ctx, QueueListURL, &listResp, acceptLanguage(queueLanguage))
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return listResp.Items, resp, nil
+}
+
+// Get returns a queue item. Jenkins keeps left items for a few minutes only.
+func (s *QueueService) Get(ctx context.Context, id int64) (*QueueItem, *http.Response, error) {
+	var item QueueItem
+	resp, err := s.client.getJSON(ctx, fmt.Sprintf(QueueItemURL, id), &item, acceptLanguage(queueLanguage))
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return &item, resp, nil
+}
+
+// Cancel removes an item from the build queue.
+func (s *QueueService) Cancel(ctx context.Context, id int64) (*http.Response, error) {
+	return s.client.postForm(ctx, QueueCancelURL, url.Values{"id": {strconv.FormatInt(id, 10)}})
+}
@@ -0,0 +1,87 @@
+package jenkins
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+func (s *Suite) TestQueueServiceList() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(QueueListURL, func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		s.Equal("en", r.Header.Get("Accept-Language"))
+		_, err := w.Write([]byte(`{"_class":"hudson.model.Queue","items":[
+			{"_class":"hudson.model.Queue$BuildableItem","id":3,"buildable":true,"inQueueSince":1700000000000,
+			 "params":"\nVERSION=v1\nDRY_RUN=true","why":"Waiting for next available executor on ‘linux’",
+			 "task":{"name":"deploy","url":"http://127.0.0.1:8080/job/deploy/"}},
+			{"_class":"hudson.model.Queue$BlockedItem","id":4,"blocked":true,"why":"Build #2 is already in progress (ETA: 1 min 2 sec)",
+			 "task":{"name":"build","url":"http://127.0.0.1:8080/job/build/"}}
+		]}`))
+		s.NoError(err)
+	})
+
+	items, _, err := client.Queue.List(context.Background())
+	s.NoError(err)
+	s.Len(items, 2)
+	s.Equal("deploy", items[0].Task.Name)
+	s.Equal("linux", items[0].Label())
+	s.Equal(map[string]string{"VERSION": "v1", "DRY_RUN": "true"}, items[0].Parameters())
+	s.Equal(time.UnixMilli(1700000000000), items[0].QueuedAt())
+	s.Equal("", items[1].Label())
+	s.Empty(items[1].Parameters())
+
+	groups := GroupByLabel(items)
+	s.Len(groups["linux"], 1)
+	s.Len(groups[""], 1)
+}
+
+func (s *Suite) TestQueueServiceListError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	_, _, err = client.Queue.List(context.Background())
+	s.Error(err)
+}
+
+func (s *Suite) TestQueueServiceGet() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.mux.HandleFunc(fmt.Sprintf(QueueItemURL, 3), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "GET")
+		s.Equal("en", r.Header.Get("Accept-Language"))
+		_, err := w.Write([]byte(`{"id":3,"why":"There are no nodes with the label ‘arm64’"}`))
+		s.NoError(err)
+	})
+
+	item, _, err := client.Queue.Get(context.Background(), 3)
+	s.NoError(err)
+	s.Equal("arm64", item.Label())
+
+	_, _, err = client.Queue.Get(context.Background(), 4)
+	s.Error(err)
+}
+
+func (s *Suite) TestQueueServiceCancel() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(QueueCancelURL, func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("3", r.FormValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	_, err = client.Queue.Cancel(context.Background(), 3)
+	s.NoError(err)
+}
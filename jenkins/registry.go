@@ -0,0 +1,153 @@
+// Copyright 2021 The go-jenkins AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jenkins
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	launchers                     = newRegistry("launcher")
+	retentionStrategies           = newRegistry("retention strategy")
+	hostKeyVerificationStrategies = newRegistry("SSH host key verification strategy")
+)
+
+func init() {
+	RegisterLauncher((*JNLPLauncher)(nil), "hudson.slaves.JNLPLauncher")
+	// Older versions of this package wrote the SSH launcher class without the plugin package.
+	RegisterLauncher((*SSHLauncher)(nil), "hudson.plugins.sshslaves.SSHLauncher", "hudson.slaves.SSHLauncher")
+	RegisterLauncher((*CommandLauncher)(nil), "hudson.slaves.CommandLauncher")
+
+	RegisterRetentionStrategy((*RetentionsStrategy)(nil), "hudson.slaves.RetentionStrategy$Always")
+	RegisterRetentionStrategy((*DemandRetentionStrategy)(nil), "hudson.slaves.RetentionStrategy$Demand")
+	RegisterRetentionStrategy((*SimpleScheduledRetentionStrategy)(nil), "hudson.slaves.SimpleScheduledRetentionStrategy")
+	RegisterRetentionStrategy((*CloudRetentionStrategy)(nil), "hudson.slaves.CloudRetentionStrategy")
+
+	RegisterSSHHostKeyVerificationStrategy((*NonVerifyingKeyVerificationStrategy)(nil),
+		"hudson.plugins.sshslaves.verifiers.NonVerifyingKeyVerificationStrategy")
+	RegisterSSHHostKeyVerificationStrategy((*KnownHostsFileKeyVerificationStrategy)(nil),
+		"hudson.plugins.sshslaves.verifiers.KnownHostsFileKeyVerificationStrategy")
+	RegisterSSHHostKeyVerificationStrategy((*ManuallyProvidedKeyVerificationStrategy)(nil),
+		"hudson.plugins.sshslaves.verifiers.ManuallyProvidedKeyVerificationStrategy")
+	RegisterSSHHostKeyVerificationStrategy((*ManuallyTrustedKeyVerificationStrategy)(nil),
+		"hudson.plugins.sshslaves.verifiers.ManuallyTrustedKeyVerificationStrategy")
+}
+
+// RegisterLauncher registers a launcher type for its stapler class. Node.UnmarshalXML decodes launchers
+// of the class, or of one of its aliases, into the type, and NodesService fills in the class of launchers
+// of the type that lack it. The launcher must be a pointer to a struct with a StaplerClass string field,
+// e.g. (*MyLauncher)(nil). Registering a class again replaces its type.
+func RegisterLauncher(launcher Launcher, class string, aliases ...string) {
+	launchers.register(launcher, class, aliases)
+}
+
+// RegisterRetentionStrategy registers a retention strategy type for its stapler class, see RegisterLauncher.
+func RegisterRetentionStrategy(strategy RetentionStrategy, class string, aliases ...string) {
+	retentionStrategies.register(strategy, class, aliases)
+}
+
+// RegisterSSHHostKeyVerificationStrategy registers an SSH host key verification strategy type
+// for its stapler class, see RegisterLauncher.
+func RegisterSSHHostKeyVerificationStrategy(strategy SSHHostKeyVerificationStrategy, class string, aliases ...string) {
+	hostKeyVerificationStrategies.register(strategy, class, aliases)
+}
+
+// registry maps stapler classes to the struct types decoding them and back.
+type registry struct {
+	kind string
+
+	mu      sync.RWMutex
+	types   map[string]reflect.Type // class or alias to type
+	classes map[string]string       // alias to class
+	byType  map[reflect.Type]string // type to class
+}
+
+func newRegistry(kind string) *registry {
+	return &registry{
+		kind:    kind,
+		types:   make(map[string]reflect.Type),
+		classes: make(map[string]string),
+		byType:  make(map[reflect.Type]string),
+	}
+}
+
+// register panics if v is not a pointer to a struct with a StaplerClass string field.
+func (r *registry) register(v interface{}, class string, aliases []string) {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("jenkins: %s %T is not a pointer to a struct", r.kind, v))
+	}
+
+	if f, ok := t.Elem().FieldByName("StaplerClass"); !ok || f.Type.Kind() != reflect.String {
+		panic(fmt.Sprintf("jenkins: %s %T has no StaplerClass string field", r.kind, v))
+	}
+
+	if class == "" {
+		panic(fmt.Sprintf("jenkins: %s %T has an empty class", r.kind, v))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.byType[t.Elem()] = class
+	for _, name := range append([]string{class}, aliases...) {
+		r.types[name] = t.Elem()
+		r.classes[name] = class
+	}
+}
+
+// decode returns a new value of the type registered for class, decoded from the inner XML of its element.
+// The class of the value is set to the registered class, which differs from class for aliases.
+// It reports false if no type is registered for class.
+func (r *registry) decode(class, plugin string, innerXML []byte) (interface{}, bool, error) {
+	r.mu.RLock()
+	t, ok := r.types[class]
+	canonical := r.classes[class]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, false, nil
+	}
+
+	v := reflect.New(t)
+
+	// Converts InnerXML to a valid XMl document
+	err := xml.Unmarshal([]byte(fmt.Sprintf("<root>%s</root>", innerXML)), v.Interface())
+	if err != nil {
+		return nil, true, err
+	}
+
+	v.Elem().FieldByName("StaplerClass").SetString(canonical)
+	if f := v.Elem().FieldByName("Plugin"); f.IsValid() && f.Kind() == reflect.String && f.CanSet() {
+		f.SetString(plugin)
+	}
+
+	return v.Interface(), true, nil
+}
+
+// fill sets the class of v to the class registered for its type unless it is set.
+func (r *registry) fill(v interface{}) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return
+	}
+
+	f := rv.Elem().FieldByName("StaplerClass")
+	if !f.IsValid() || f.Kind() != reflect.String || f.String() != "" {
+		return
+	}
+
+	r.mu.RLock()
+	class, ok := r.byType[rv.Elem().Type()]
+	r.mu.RUnlock()
+
+	if ok {
+		f.SetString(class)
+	}
+}
@@ -0,0 +1,152 @@
+package jenkins
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type registryTestLauncher struct {
+	StaplerClass string `json:"stapler-class" xml:"class,attr"`
+	Plugin       string `json:"-" xml:"plugin,attr,omitempty"`
+
+	Endpoint string `json:"endpoint" xml:"endpoint"`
+}
+
+type registryTestRetentionStrategy struct {
+	StaplerClass string `json:"stapler-class" xml:"class,attr"`
+
+	Delay int `json:"delay" xml:"delay"`
+}
+
+type registryTestHostKeyVerificationStrategy struct {
+	StaplerClass string `json:"stapler-class" xml:"class,attr"`
+
+	Fingerprint string `json:"fingerprint" xml:"fingerprint"`
+}
+
+func init() {
+	RegisterLauncher((*registryTestLauncher)(nil), "com.example.TestLauncher", "com.example.LegacyTestLauncher")
+	RegisterRetentionStrategy((*registryTestRetentionStrategy)(nil), "com.example.TestRetentionStrategy")
+	RegisterSSHHostKeyVerificationStrategy((*registryTestHostKeyVerificationStrategy)(nil), "com.example.TestKeyVerificationStrategy")
+}
+
+func (s *Suite) TestRegistryDecodeNode() {
+	inputXML := `<slave>
+  <name>test</name>
+  <launcher class="com.example.LegacyTestLauncher" plugin="test@1.0">
+    <endpoint>https://agents.example.com</endpoint>
+  </launcher>
+  <retentionStrategy class="com.example.TestRetentionStrategy">
+    <delay>5</delay>
+  </retentionStrategy>
+</slave>`
+	var node Node
+	err := xml.Unmarshal([]byte(inputXML), &node)
+	s.NoError(err)
+
+	s.Equal(&registryTestLauncher{
+		StaplerClass: "com.example.TestLauncher",
+		Plugin:       "test@1.0",
+		Endpoint:     "https://agents.example.com",
+	}, node.Launcher)
+	s.Equal(&registryTestRetentionStrategy{
+		StaplerClass: "com.example.TestRetentionStrategy",
+		Delay:        5,
+	}, node.RetentionsStrategy)
+}
+
+func (s *Suite) TestRegistryDecodeSSHLauncher() {
+	inputXML := `<slave>
+  <launcher class="hudson.slaves.SSHLauncher">
+    <host>agent</host>
+    <sshHostKeyVerificationStrategy class="com.example.TestKeyVerificationStrategy">
+      <fingerprint>SHA256:abc</fingerprint>
+    </sshHostKeyVerificationStrategy>
+  </launcher>
+  <retentionStrategy class="com.example.UnknownRetentionStrategy"/>
+</slave>`
+	var node Node
+	err := xml.Unmarshal([]byte(inputXML), &node)
+	s.NoError(err)
+
+	launcher, ok := node.Launcher.(*SSHLauncher)
+	s.True(ok)
+	s.Equal("hudson.plugins.sshslaves.SSHLauncher", launcher.StaplerClass)
+	s.Equal("agent", launcher.Host)
+	s.Equal(&registryTestHostKeyVerificationStrategy{
+		StaplerClass: "com.example.TestKeyVerificationStrategy",
+		Fingerprint:  "SHA256:abc",
+	}, launcher.SSHHostKeyVerificationStrategy)
+	s.Equal(&RetentionsStrategy{StaplerClass: "com.example.UnknownRetentionStrategy"}, node.RetentionsStrategy)
+
+	var unknown SSHLauncher
+	err = xml.Unmarshal([]byte(`<launcher><sshHostKeyVerificationStrategy class="com.example.Unknown"/></launcher>`), &unknown)
+	s.NoError(err)
+	s.Nil(unknown.SSHHostKeyVerificationStrategy)
+}
+
+func (s *Suite) TestNodeFillInClasses() {
+	node := &Node{
+		Launcher: &SSHLauncher{
+			SSHHostKeyVerificationStrategy: &NonVerifyingKeyVerificationStrategy{},
+		},
+		RetentionsStrategy: &DemandRetentionStrategy{},
+	}
+	node.fillInClasses()
+
+	s.Equal("hudson.plugins.sshslaves.SSHLauncher", node.Launcher.(*SSHLauncher).StaplerClass)
+	s.Equal("hudson.plugins.sshslaves.verifiers.NonVerifyingKeyVerificationStrategy",
+		node.Launcher.(*SSHLauncher).SSHHostKeyVerificationStrategy.(*NonVerifyingKeyVerificationStrategy).StaplerClass)
+	s.Equal("hudson.slaves.RetentionStrategy$Demand", node.RetentionsStrategy.(*DemandRetentionStrategy).StaplerClass)
+
+	custom := &Node{
+		Launcher:           &registryTestLauncher{StaplerClass: "com.example.OtherLauncher"},
+		RetentionsStrategy: &registryTestRetentionStrategy{},
+	}
+	custom.fillInClasses()
+
+	s.Equal("com.example.OtherLauncher", custom.Launcher.(*registryTestLauncher).StaplerClass)
+	s.Equal("com.example.TestRetentionStrategy", custom.RetentionsStrategy.(*registryTestRetentionStrategy).StaplerClass)
+}
+
+func (s *Suite) TestNodesServiceUpdateFillsInClasses() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+
+	s.mux.HandleFunc(fmt.Sprintf(NodesGetURL, "test"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		body, err := io.ReadAll(r.Body)
+		s.NoError(err)
+		s.Contains(string(body), `<launcher class="com.example.TestLauncher"><endpoint>https://agents.example.com</endpoint></launcher>`)
+		s.Contains(string(body), `<retentionStrategy class="hudson.slaves.CloudRetentionStrategy">`)
+	})
+
+	_, _, err = client.Nodes.Update(context.Background(), &Node{
+		Name:               "test",
+		Launcher:           &registryTestLauncher{Endpoint: "https://agents.example.com"},
+		RetentionsStrategy: &CloudRetentionStrategy{IdleMinutes: 10},
+	})
+
+	s.NoError(err)
+}
+
+func (s *Suite) TestRegisterInvalidType() {
+	s.Panics(func() {
+		RegisterLauncher(registryTestLauncher{}, "com.example.Value")
+	})
+	s.Panics(func() {
+		RegisterRetentionStrategy(&struct{ Class string }{}, "com.example.NoStaplerClass")
+	})
+	s.Panics(func() {
+		RegisterSSHHostKeyVerificationStrategy(nil, "com.example.Nil")
+	})
+	s.Panics(func() {
+		RegisterLauncher((*registryTestLauncher)(nil), "")
+	})
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 The go-jenkins AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jenkins
+
+// RetentionStrategy is the interface for all Jenkins node retention strategies,
+// which control when the agent of a node is connected.
+type RetentionStrategy interface{}
+
+// RetentionsStrategy represents a Jenkins node retention strategy without configuration,
+// e.g. RetentionStrategy$Always.
+type RetentionsStrategy struct {
+	StaplerClass string `json:"stapler-class" xml:"class,attr"`
+}
+
+// DefaultRetentionsStrategy represents the default retention strategy, which keeps the agent connected.
+func DefaultRetentionsStrategy() *RetentionsStrategy {
+	return &RetentionsStrategy{StaplerClass: "hudson.slaves.RetentionStrategy$Always"}
+}
+
+// DemandRetentionStrategy represents a retention strategy connecting the agent when builds are waiting
+// and disconnecting it when it is idle. The delays are in minutes.
+type DemandRetentionStrategy struct {
+	StaplerClass string `json:"stapler-class" xml:"class,attr"`
+
+	InDemandDelay int `json:"inDemandDelay" xml:"inDemandDelay"`
+	IdleDelay     int `json:"idleDelay" xml:"idleDelay"`
+}
+
+// NewDemandRetentionStrategy returns a retention strategy connecting the agent once builds have been waiting
+// for inDemandDelay minutes and disconnecting it after it has been idle for idleDelay minutes.
+func NewDemandRetentionStrategy(inDemandDelay, idleDelay int) *DemandRetentionStrategy {
+	return &DemandRetentionStrategy{
+		StaplerClass:  "hudson.slaves.RetentionStrategy$Demand",
+		InDemandDelay: inDemandDelay,
+		IdleDelay:     idleDelay,
+	}
+}
+
+// SimpleScheduledRetentionStrategy represents a retention strategy connecting the agent on a schedule.
+type SimpleScheduledRetentionStrategy struct {
+	StaplerClass string `json:"stapler-class" xml:"class,attr"`
+
+	StartTimeSpec    string `json:"startTimeSpec" xml:"startTimeSpec"`
+	UpTimeMins       int    `json:"upTimeMins" xml:"upTimeMins"`
+	KeepUpWhenActive bool   `json:"keepUpWhenActive" xml:"keepUpWhenActive"`
+}
+
+// NewSimpleScheduledRetentionStrategy returns a retention strategy connecting the agent at the times
+// of the cron spec startTimeSpec, e.g. "0 8 * * 1-5", for upTimeMins minutes.
+// If keepUpWhenActive is set, the agent stays connected until its builds have finished.
+func NewSimpleScheduledRetentionStrategy(startTimeSpec string, upTimeMins int, keepUpWhenActive bool) *SimpleScheduledRetentionStrategy {
+	return &SimpleScheduledRetentionStrategy{
+		StaplerClass:     "hudson.slaves.SimpleScheduledRetentionStrategy",
+		StartTimeSpec:    startTimeSpec,
+		UpTimeMins:       upTimeMins,
+		KeepUpWhenActive: keepUpWhenActive,
+	}
+}
+
+// CloudRetentionStrategy represents the retention strategy of cloud agents,
+// which terminates the agent after it has been idle for IdleMinutes.
+type CloudRetentionStrategy struct {
+	StaplerClass string `json:"stapler-class" xml:"class,attr"`
+
+	IdleMinutes int `json:"idleMinutes" xml:"idleMinutes"`
+}
+
+// NewCloudRetentionStrategy returns a retention strategy terminating a cloud agent after idleMinutes.
+func NewCloudRetentionStrategy(idleMinutes int) *CloudRetentionStrategy {
+	return &CloudRetentionStrategy{
+		StaplerClass: "hudson.slaves.CloudRetentionStrategy",
+		IdleMinutes:  idleMinutes,
+	}
+}
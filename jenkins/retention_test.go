@@ -0,0 +1,69 @@
+package jenkins
+
+import (
+	"encoding/json"
+	"encoding/xml"
+)
+
+func (s *Suite) TestNodeUnmarshalRetentionStrategies() {
+	tests := []struct {
+		strategy string
+		want     RetentionStrategy
+	}{
+		{
+			strategy: `<retentionStrategy class="hudson.slaves.RetentionStrategy$Always"/>`,
+			want:     DefaultRetentionsStrategy(),
+		},
+		{
+			strategy: `<retentionStrategy class="hudson.slaves.RetentionStrategy$Demand">
+    <inDemandDelay>2</inDemandDelay>
+    <idleDelay>15</idleDelay>
+  </retentionStrategy>`,
+			want: NewDemandRetentionStrategy(2, 15),
+		},
+		{
+			strategy: `<retentionStrategy class="hudson.slaves.SimpleScheduledRetentionStrategy">
+    <startTimeSpec>0 8 * * 1-5</startTimeSpec>
+    <upTimeMins>600</upTimeMins>
+    <keepUpWhenActive>true</keepUpWhenActive>
+  </retentionStrategy>`,
+			want: NewSimpleScheduledRetentionStrategy("0 8 * * 1-5", 600, true),
+		},
+		{
+			strategy: `<retentionStrategy class="hudson.slaves.CloudRetentionStrategy"><idleMinutes>10</idleMinutes></retentionStrategy>`,
+			want:     NewCloudRetentionStrategy(10),
+		},
+		{
+			strategy: `<retentionStrategy class="org.example.CustomRetentionStrategy"><whatever/></retentionStrategy>`,
+			want:     &RetentionsStrategy{StaplerClass: "org.example.CustomRetentionStrategy"},
+		},
+		{
+			strategy: ``,
+			want:     nil,
+		},
+	}
+
+	for _, test := range tests {
+		var node Node
+		err := xml.Unmarshal([]byte(`<slave><name>test</name>`+test.strategy+`</slave>`), &node)
+		s.NoError(err)
+		s.Equal("test", node.Name)
+		s.Equal(test.want, node.RetentionsStrategy)
+	}
+}
+
+func (s *Suite) TestNodeMarshalRetentionStrategy() {
+	node := &Node{Name: "test", RetentionsStrategy: NewDemandRetentionStrategy(0, 5)}
+
+	b, err := xml.Marshal(node)
+	s.NoError(err)
+	s.Contains(string(b), `<retentionStrategy class="hudson.slaves.RetentionStrategy$Demand"><inDemandDelay>0</inDemandDelay><idleDelay>5</idleDelay></retentionStrategy>`)
+
+	var decoded Node
+	s.NoError(xml.Unmarshal(b, &decoded))
+	s.Equal(node.RetentionsStrategy, decoded.RetentionsStrategy)
+
+	b, err = json.Marshal(&Node{Name: "test", RetentionsStrategy: NewSimpleScheduledRetentionStrategy("H 8 * * *", 60, false)})
+	s.NoError(err)
+	s.Contains(string(b), `"retentionStrategy":{"stapler-class":"hudson.slaves.SimpleScheduledRetentionStrategy","startTimeSpec":"H 8 * * *","upTimeMins":60,"keepUpWhenActive":false}`)
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 The go-jenkins AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jenkins
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	// ScriptURL is the URL to run a Groovy script in the script console
+	ScriptURL = "/scriptText"
+	// NodesScriptURL is the URL to run a Groovy script on an agent
+	NodesScriptURL = "/computer/%s/scriptText"
+)
+
+// scriptExceptionRegexp matches the first line of a Java stack trace, e.g.
+// "groovy.lang.MissingPropertyException: No such property: foo for class: Script1".
+var scriptExceptionRegexp = regexp.MustCompile(`^((?:[a-zA-Z_$][\w$]*\.)+[\w$]*(?:Exception|Error|Throwable)[\w$]*)(?::\s?(.*))?$`)
+
+// ScriptError is returned for a Groovy script that threw an exception. The script console responds
+// with 200 OK and the stack trace in the output then, unlike for HTTP errors.
+type ScriptError struct {
+	// Exception is the class of the exception, e.g. "groovy.lang.MissingPropertyException".
+	Exception string
+	// Message is the message of the exception.
+	Message string
+	// Output is the whole output of the script, including what it printed before the exception.
+	Output string
+}
+
+func (e *ScriptError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("script error: %s", e.Exception)
+	}
+
+	return fmt.Sprintf("script error: %s: %s", e.Exception, e.Message)
+}
+
+// parseScriptError returns a ScriptError if the output contains a stack trace. The message spans
+// the lines up to the first stack frame, e.g. for compilation errors. An exception line is only taken
+// as such if a stack frame follows, so a script printing a line that looks like an exception does not fail.
+func parseScriptError(output string) *ScriptError {
+	lines := strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		m := scriptExceptionRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		for j := i + 1; j < len(lines); j++ {
+			if isStackFrame(lines[j]) {
+				message := append([]string{m[2]}, lines[i+1:j]...)
+				return &ScriptError{
+					Exception: m[1],
+					Message:   strings.TrimSpace(strings.Join(message, "\n")),
+					Output:    output,
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// isStackFrame reports whether line is a frame of a Java stack trace, e.g. "\tat Script1.run(Script1.groovy:1)".
+func isStackFrame(line string) bool {
+	return strings.HasPrefix(line, "\tat ") || strings.HasPrefix(line, "    at ")
+}
+
+// Script runs a Groovy script in the script console of the controller and returns its output.
+// Running scripts requires the Overall/Administer permission. A *ScriptError is returned
+// together with the output if the script threw an exception.
+func (c *Client) Script(ctx context.Context, script string) (string, *http.Response, error) {
+	return c.script(ctx, ScriptURL, script)
+}
+
+// Script runs a Groovy script on an agent and returns its output, see Client.Script.
+func (s *NodesService) Script(ctx context.Context, name, script string) (string, *http.Response, error) {
+	return s.client.script(ctx, fmt.Sprintf(NodesScriptURL, name), script)
+}
+
+func (c *Client) script(ctx context.Context, path, script string) (string, *http.Response, error) {
+	resp, err := c.postForm(ctx, path, url.Values{"script": {script}})
+	if err != nil {
+		return "", resp, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", resp, err
+	}
+
+	output := string(body)
+	if scriptErr := parseScriptError(output); scriptErr != nil {
+		return output, resp, scriptErr
+	}
+
+	return output, resp, nil
+}
@@ -0,0 +1,141 @@
+package jenkins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+func (s *Suite) TestClientScript() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+	s.mux.HandleFunc(ScriptURL, func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal("crumb", r.Header.Get("crumb"))
+		s.Equal(`println("Jenkins " + Jenkins.VERSION)`, r.FormValue("script"))
+		_, err := w.Write([]byte("Jenkins 2.401.1\n"))
+		s.NoError(err)
+	})
+
+	output, resp, err := client.Script(context.Background(), `println("Jenkins " + Jenkins.VERSION)`)
+	s.NoError(err)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal("Jenkins 2.401.1\n", output)
+}
+
+func (s *Suite) TestNodesServiceScript() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+	s.mux.HandleFunc(fmt.Sprintf(NodesScriptURL, "agent-1"), func(w http.ResponseWriter, r *http.Request) {
+		s.testMethod(r, "POST")
+		s.Equal(`println(System.getProperty("os.name"))`, r.FormValue("script"))
+		_, err := w.Write([]byte("Linux\n"))
+		s.NoError(err)
+	})
+
+	output, _, err := client.Nodes.Script(context.Background(), "agent-1", `println(System.getProperty("os.name"))`)
+	s.NoError(err)
+	s.Equal("Linux\n", output)
+}
+
+func (s *Suite) TestClientScriptError() {
+	tests := []struct {
+		name   string
+		output string
+		want   *ScriptError
+	}{
+		{
+			name: "runtime exception",
+			output: `started
+groovy.lang.MissingPropertyException: No such property: foo for class: Script1
+	at org.codehaus.groovy.runtime.ScriptBytecodeAdapter.unwrap(ScriptBytecodeAdapter.java:66)
+	at Script1.run(Script1.groovy:2)
+`,
+			want: &ScriptError{
+				Exception: "groovy.lang.MissingPropertyException",
+				Message:   "No such property: foo for class: Script1",
+			},
+		},
+		{
+			name: "compilation error",
+			output: `org.codehaus.groovy.control.MultipleCompilationErrorsException: startup failed:
+Script1.groovy: 1: unexpected token: } @ line 1, column 1.
+   }
+   ^
+
+1 error
+
+	at org.codehaus.groovy.control.ErrorCollector.failIfErrors(ErrorCollector.java:311)
+`,
+			want: &ScriptError{
+				Exception: "org.codehaus.groovy.control.MultipleCompilationErrorsException",
+				Message:   "startup failed:\nScript1.groovy: 1: unexpected token: } @ line 1, column 1.\n   }\n   ^\n\n1 error",
+			},
+		},
+		{
+			name: "exception without message",
+			output: "java.lang.NullPointerException\r\n" +
+				"\tat Script1.run(Script1.groovy:1)\r\n",
+			want: &ScriptError{
+				Exception: "java.lang.NullPointerException",
+			},
+		},
+		{
+			name:   "printed exception",
+			output: "java.lang.IllegalStateException: expected in the output\nok\n",
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			s.newMux()
+			client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+			s.NoError(err)
+
+			s.addCrumbsHandle()
+			s.mux.HandleFunc(ScriptURL, func(w http.ResponseWriter, r *http.Request) {
+				_, err := w.Write([]byte(tt.output))
+				s.NoError(err)
+			})
+
+			output, _, err := client.Script(context.Background(), "script")
+			s.Equal(tt.output, output)
+
+			if tt.want == nil {
+				s.NoError(err)
+				return
+			}
+
+			var scriptErr *ScriptError
+			s.True(errors.As(err, &scriptErr))
+			s.Equal(tt.want.Exception, scriptErr.Exception)
+			s.Equal(tt.want.Message, scriptErr.Message)
+			s.Equal(tt.output, scriptErr.Output)
+		})
+	}
+}
+
+func (s *Suite) TestClientScriptHTTPError() {
+	s.newMux()
+	client, err := NewClient(WithBaseURL(s.server.URL), WithUserPassword("admin", "admin"))
+	s.NoError(err)
+
+	s.addCrumbsHandle()
+	s.mux.HandleFunc(ScriptURL, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	_, resp, err := client.Script(context.Background(), "println(1)")
+	s.EqualError(err, "HTTP error: 403 Forbidden")
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+
+	var scriptErr *ScriptError
+	s.False(errors.As(err, &scriptErr))
+}
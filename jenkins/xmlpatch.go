@@ -0,0 +1,434 @@
+// Copyright 2021 The go-jenkins AUTHORS. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jenkins
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// xmlElement is an element of a minimal XML document tree built from raw tokens.
+// Unlike encoding/xml with structs, it keeps every element, attribute, comment and whitespace,
+// so a document is written back the way it was read.
+type xmlElement struct {
+	Name     xml.Name
+	Attr     []xml.Attr
+	Children []xmlNode
+}
+
+// xmlNode is a child of an xmlElement, either an *xmlElement or one of the xml.CharData,
+// xml.Comment, xml.ProcInst and xml.Directive tokens.
+type xmlNode interface{}
+
+// parseXMLDocument parses an XML document into a tree. The returned element is a synthetic
+// document element holding the root element and the prolog, e.g. the XML declaration.
+func parseXMLDocument(data []byte) (*xmlElement, error) {
+	doc := &xmlElement{}
+	stack := []*xmlElement{doc}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parent := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e := &xmlElement{Name: t.Name, Attr: t.Attr}
+			parent.Children = append(parent.Children, e)
+			stack = append(stack, e)
+		case xml.EndElement:
+			if len(stack) == 1 {
+				return nil, errors.New("unexpected end element " + t.Name.Local)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			parent.Children = append(parent.Children, xml.CopyToken(tok))
+		}
+	}
+
+	if len(stack) != 1 || doc.root() == nil {
+		return nil, errors.New("incomplete XML document")
+	}
+
+	return doc, nil
+}
+
+// root returns the first child element of e.
+func (e *xmlElement) root() *xmlElement {
+	for _, c := range e.Children {
+		if el, ok := c.(*xmlElement); ok {
+			return el
+		}
+	}
+
+	return nil
+}
+
+// elements returns the child elements of e with the given local name.
+func (e *xmlElement) elements(name string) []*xmlElement {
+	var elements []*xmlElement
+	for _, c := range e.Children {
+		if el, ok := c.(*xmlElement); ok && el.Name.Local == name {
+			elements = append(elements, el)
+		}
+	}
+
+	return elements
+}
+
+// elementNames returns the distinct local names of the child elements of e in document order.
+func (e *xmlElement) elementNames() []string {
+	seen := make(map[string]bool)
+
+	var names []string
+	for _, c := range e.Children {
+		if el, ok := c.(*xmlElement); ok && !seen[el.Name.Local] {
+			seen[el.Name.Local] = true
+			names = append(names, el.Name.Local)
+		}
+	}
+
+	return names
+}
+
+// isLeaf reports whether e has no child elements.
+func (e *xmlElement) isLeaf() bool {
+	for _, c := range e.Children {
+		if _, ok := c.(*xmlElement); ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// attr returns the value of the attribute of e with the given local name.
+func (e *xmlElement) attr(name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+
+	return ""
+}
+
+// text returns the character data of e with whitespace between child elements dropped.
+func (e *xmlElement) text() string {
+	var b bytes.Buffer
+	for _, c := range e.Children {
+		if t, ok := c.(xml.CharData); ok {
+			b.Write(t)
+		}
+	}
+
+	if e.isLeaf() {
+		return b.String()
+	}
+
+	return string(bytes.TrimSpace(b.Bytes()))
+}
+
+// equalXMLElements reports whether a and b have the same names, attributes, text and child elements.
+// Comments and whitespace between child elements are ignored.
+func equalXMLElements(a, b *xmlElement) bool {
+	if a.Name.Local != b.Name.Local || len(a.Attr) != len(b.Attr) || a.text() != b.text() {
+		return false
+	}
+
+	for _, attr := range a.Attr {
+		if b.attr(attr.Name.Local) != attr.Value {
+			return false
+		}
+	}
+
+	return equalXMLGroups(childElements(a), childElements(b))
+}
+
+func equalXMLGroups(a, b []*xmlElement) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !equalXMLElements(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func childElements(e *xmlElement) []*xmlElement {
+	var elements []*xmlElement
+	for _, c := range e.Children {
+		if el, ok := c.(*xmlElement); ok {
+			elements = append(elements, el)
+		}
+	}
+
+	return elements
+}
+
+// patchXMLElement applies the changes between the snapshot and the modified version of an element to
+// the original element it was decoded from. Child elements are matched by name:
+//   - children equal in snapshot and modified are kept as they are in original,
+//   - text of leaf elements is replaced,
+//   - changed children are patched recursively if they occur once with the same class,
+//     and replaced by the modified ones otherwise,
+//   - children added in modified are appended and children dropped from modified are removed,
+//   - children of original missing from both snapshot and modified, i.e. elements not modelled
+//     by the decoded type, are kept.
+func patchXMLElement(original, snapshot, modified *xmlElement) {
+	for _, a := range modified.Attr {
+		if snapshot.attr(a.Name.Local) != a.Value {
+			original.setAttr(a)
+		}
+	}
+
+	for _, a := range snapshot.Attr {
+		if modified.attr(a.Name.Local) == "" && a.Value != "" {
+			original.removeAttr(a.Name.Local)
+		}
+	}
+
+	// Text is replaced as a whole. An element that merely has no modelled children left, e.g. an empty
+	// <nodeProperties>, is merged by name below, so children only present in original are kept.
+	if snapshot.isLeaf() && modified.isLeaf() {
+		if !equalXMLElements(snapshot, modified) {
+			original.Children = modified.Children
+		}
+		return
+	}
+
+	names := modified.elementNames()
+	for _, name := range snapshot.elementNames() {
+		if len(modified.elements(name)) == 0 {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		snapshots, modifications, originals := snapshot.elements(name), modified.elements(name), original.elements(name)
+		if equalXMLGroups(snapshots, modifications) {
+			continue
+		}
+
+		if len(snapshots) == 1 && len(modifications) == 1 && len(originals) == 1 &&
+			snapshots[0].attr("class") == modifications[0].attr("class") {
+			patchXMLElement(originals[0], snapshots[0], modifications[0])
+			continue
+		}
+
+		original.replaceElements(name, modifications)
+	}
+}
+
+func (e *xmlElement) setAttr(attr xml.Attr) {
+	for i := range e.Attr {
+		if e.Attr[i].Name.Local == attr.Name.Local {
+			e.Attr[i].Value = attr.Value
+			return
+		}
+	}
+
+	e.Attr = append(e.Attr, attr)
+}
+
+func (e *xmlElement) removeAttr(name string) {
+	attrs := e.Attr[:0]
+	for _, a := range e.Attr {
+		if a.Name.Local != name {
+			attrs = append(attrs, a)
+		}
+	}
+
+	e.Attr = attrs
+}
+
+// replaceElements replaces the child elements of e with the given name by elements.
+// The elements take the place of the first replaced element, or are appended after the last child element.
+// Each element is indented like the element it replaces or follows.
+func (e *xmlElement) replaceElements(name string, elements []*xmlElement) {
+	var children []xmlNode
+
+	inserted := false
+	for _, c := range e.Children {
+		el, ok := c.(*xmlElement)
+		if !ok || el.Name.Local != name {
+			children = append(children, c)
+			continue
+		}
+
+		var indent xmlNode
+		if n := len(children); n > 0 && isXMLWhitespace(children[n-1]) {
+			indent = children[n-1]
+			children = children[:n-1]
+		}
+
+		if !inserted {
+			children = appendIndented(children, indent, elements)
+			inserted = true
+		}
+	}
+
+	if !inserted {
+		last := len(children)
+		for last > 0 {
+			if _, ok := children[last-1].(*xmlElement); ok {
+				break
+			}
+			last--
+		}
+
+		var indent xmlNode
+		if last > 1 && isXMLWhitespace(children[last-2]) {
+			indent = children[last-2]
+		}
+
+		// Keeps the whitespace before the end tag of e after the appended elements.
+		tail := append([]xmlNode{}, children[last:]...)
+		children = append(appendIndented(children[:last], indent, elements), tail...)
+	}
+
+	e.Children = children
+}
+
+func appendIndented(children []xmlNode, indent xmlNode, elements []*xmlElement) []xmlNode {
+	for _, el := range elements {
+		if indent != nil {
+			children = append(children, indent)
+		}
+		children = append(children, el)
+	}
+
+	return children
+}
+
+// isXMLWhitespace reports whether n is character data consisting of whitespace only.
+func isXMLWhitespace(n xmlNode) bool {
+	t, ok := n.(xml.CharData)
+	return ok && len(bytes.TrimSpace(t)) == 0
+}
+
+// writeXML writes the tree rooted at e. The synthetic document element itself is not written.
+func (e *xmlElement) writeXML(b *bytes.Buffer, document bool) {
+	if !document {
+		b.WriteByte('<')
+		writeXMLName(b, e.Name)
+		for _, a := range e.Attr {
+			b.WriteByte(' ')
+			writeXMLName(b, a.Name)
+			b.WriteString(`="`)
+			writeXMLText(b, []byte(a.Value), true)
+			b.WriteByte('"')
+		}
+
+		if len(e.Children) == 0 {
+			b.WriteString("/>")
+			return
+		}
+		b.WriteByte('>')
+	}
+
+	for _, c := range e.Children {
+		switch t := c.(type) {
+		case *xmlElement:
+			t.writeXML(b, false)
+		case xml.CharData:
+			writeXMLText(b, t, false)
+		case xml.Comment:
+			b.WriteString("<!--")
+			b.Write(t)
+			b.WriteString("-->")
+		case xml.ProcInst:
+			b.WriteString("<?")
+			b.WriteString(t.Target)
+			if len(t.Inst) > 0 {
+				b.WriteByte(' ')
+				b.Write(t.Inst)
+			}
+			b.WriteString("?>")
+		case xml.Directive:
+			b.WriteString("<!")
+			b.Write(t)
+			b.WriteByte('>')
+		}
+	}
+
+	if !document {
+		b.WriteString("</")
+		writeXMLName(b, e.Name)
+		b.WriteByte('>')
+	}
+}
+
+// writeXMLText escapes text for XML. Unlike xml.EscapeText, it keeps newlines and tabs of character data,
+// so the indentation of a document is written back unchanged.
+func writeXMLText(b *bytes.Buffer, text []byte, attr bool) {
+	for _, c := range string(text) {
+		switch {
+		case c == '&':
+			b.WriteString("&amp;")
+		case c == '<':
+			b.WriteString("&lt;")
+		case c == '>':
+			b.WriteString("&gt;")
+		case c == '"' && attr:
+			b.WriteString("&quot;")
+		case c == '\n' && attr:
+			b.WriteString("&#xA;")
+		case c == '\r':
+			b.WriteString("&#xD;")
+		case c == '\t' && attr:
+			b.WriteString("&#x9;")
+		default:
+			b.WriteRune(c)
+		}
+	}
+}
+
+func writeXMLName(b *bytes.Buffer, name xml.Name) {
+	if name.Space != "" {
+		b.WriteString(name.Space)
+		b.WriteByte(':')
+	}
+	b.WriteString(name.Local)
+}
+
+// patchXML applies the changes between snapshot and modified to original, see patchXMLElement.
+// All three are XML documents with the same root element.
+func patchXML(original, snapshot, modified []byte) ([]byte, error) {
+	originalDoc, err := parseXMLDocument(original)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotDoc, err := parseXMLDocument(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	modifiedDoc, err := parseXMLDocument(modified)
+	if err != nil {
+		return nil, err
+	}
+
+	patchXMLElement(originalDoc.root(), snapshotDoc.root(), modifiedDoc.root())
+
+	var b bytes.Buffer
+	originalDoc.writeXML(&b, true)
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,76 @@
+package jenkins
+
+func (s *Suite) TestPatchXML() {
+	original := `<?xml version='1.0' encoding='UTF-8'?>
+<slave>
+  <!-- managed by hand -->
+  <name>agent-1</name>
+  <description>old</description>
+  <launcher class="hudson.slaves.JNLPLauncher" plugin="remoting@1.0">
+    <webSocket>false</webSocket>
+    <tunnel>proxy:50000</tunnel>
+  </launcher>
+  <label>a</label>
+  <label>b</label>
+  <userId>admin</userId>
+</slave>`
+	snapshot := `<slave><name>agent-1</name><description>old</description><launcher class="hudson.slaves.JNLPLauncher"><webSocket>false</webSocket></launcher><label>a</label><label>b</label></slave>`
+
+	tests := []struct {
+		name     string
+		modified string
+		want     string
+	}{
+		{
+			name:     "unchanged",
+			modified: snapshot,
+			want:     original,
+		},
+		{
+			name:     "changed text",
+			modified: `<slave><name>agent-1</name><description>new &amp; "quoted"</description><launcher class="hudson.slaves.JNLPLauncher"><webSocket>true</webSocket></launcher><label>a</label><label>b</label></slave>`,
+			want: `<?xml version='1.0' encoding='UTF-8'?>
+<slave>
+  <!-- managed by hand -->
+  <name>agent-1</name>
+  <description>new &amp; "quoted"</description>
+  <launcher class="hudson.slaves.JNLPLauncher" plugin="remoting@1.0">
+    <webSocket>true</webSocket>
+    <tunnel>proxy:50000</tunnel>
+  </launcher>
+  <label>a</label>
+  <label>b</label>
+  <userId>admin</userId>
+</slave>`,
+		},
+		{
+			name:     "changed class, list and added and removed elements",
+			modified: `<slave><name>agent-1</name><launcher class="hudson.slaves.CommandLauncher"><agentCommand>ssh agent</agentCommand></launcher><label>c</label><remoteFS>/srv</remoteFS></slave>`,
+			want: `<?xml version='1.0' encoding='UTF-8'?>
+<slave>
+  <!-- managed by hand -->
+  <name>agent-1</name>
+  <launcher class="hudson.slaves.CommandLauncher"><agentCommand>ssh agent</agentCommand></launcher>
+  <label>c</label>
+  <userId>admin</userId>
+  <remoteFS>/srv</remoteFS>
+</slave>`,
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			got, err := patchXML([]byte(original), []byte(snapshot), []byte(tt.modified))
+			s.NoError(err)
+			s.Equal(tt.want, string(got))
+		})
+	}
+}
+
+func (s *Suite) TestPatchXMLError() {
+	_, err := patchXML([]byte(`<slave>`), []byte(`<slave/>`), []byte(`<slave/>`))
+	s.Error(err)
+
+	_, err = patchXML([]byte(`<slave/>`), []byte(`<slave/>`), []byte(`</slave>`))
+	s.Error(err)
+}